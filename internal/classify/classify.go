@@ -2,6 +2,7 @@ package classify
 
 import (
 	"context"
+	"fmt"
 	"strings"
 
 	"github.com/okJiang/flaky-test-cleaner/internal/extract"
@@ -21,6 +22,8 @@ type Result struct {
 	Class       Class
 	Confidence  float64
 	Explanation string
+	Citations   []string
+	Votes       []Vote
 }
 
 type Classifier interface {
@@ -28,64 +31,41 @@ type Classifier interface {
 }
 
 type Heuristic struct {
-	threshold float64
+	rules RuleSource
 }
 
-func NewHeuristic(threshold float64) *Heuristic { return &Heuristic{threshold: threshold} }
+func NewHeuristic() *Heuristic {
+	return &Heuristic{rules: DefaultRules()}
+}
+
+func NewHeuristicWithRules(rules RuleSource) *Heuristic {
+	return &Heuristic{rules: rules}
+}
 
 func (h *Heuristic) Classify(ctx context.Context, st store.Store, occ extract.Occurrence) (Result, error) {
-	text := strings.ToLower(strings.TrimSpace(occ.ErrorSignature + "\n" + occ.Excerpt))
+	text := strings.TrimSpace(occ.ErrorSignature + "\n" + occ.Excerpt)
 	if text == "" {
 		return Result{Class: ClassUnknown, Confidence: 0.4, Explanation: "no signal in logs"}, nil
 	}
-	if containsAny(text, infraKeywords) {
-		return Result{Class: ClassInfraFlake, Confidence: 0.9, Explanation: "matched infra/network keyword"}, nil
+	hits := h.rules.Current().Match(occ)
+	if len(hits) == 0 {
+		return Result{Class: ClassUnknown, Confidence: 0.5, Explanation: "no rule matched"}, nil
 	}
-	if containsAny(text, regressionKeywords) {
-		return Result{Class: ClassLikelyRegression, Confidence: 0.85, Explanation: "matched build/compile keyword"}, nil
-	}
-	if containsAny(text, flakyKeywords) {
-		return Result{Class: ClassFlakyTest, Confidence: 0.8, Explanation: "matched flaky/timeout/race keyword"}, nil
+	best := hits[0]
+	explanation := fmt.Sprintf("rule %s matched %s %q", best.Rule.Name, best.Rule.Field, truncate(best.Match, 80))
+	if len(hits) > 1 {
+		var others []string
+		for _, hit := range hits[1:] {
+			others = append(others, fmt.Sprintf("%s→%s", hit.Rule.Name, hit.Rule.Class))
+		}
+		explanation += "; also matched " + strings.Join(others, ", ")
 	}
-	return Result{Class: ClassUnknown, Confidence: 0.5, Explanation: "no strong heuristic match"}, nil
-}
-
-var infraKeywords = []string{
-	"connection reset",
-	"broken pipe",
-	"dial tcp",
-	"tls handshake timeout",
-	"i/o timeout",
-	"no space left on device",
-	"network is unreachable",
-	"temporary failure",
-	"runner lost",
-	"operation timed out",
-}
-
-var regressionKeywords = []string{
-	"undefined:",
-	"cannot find",
-	"build failed",
-	"compile",
-	"syntax error",
-	"missing module",
-	"no required module provides package",
+	return Result{Class: best.Rule.Class, Confidence: best.Rule.Confidence, Explanation: explanation}, nil
 }
 
-var flakyKeywords = []string{
-	"data race",
-	"panic:",
-	"timeout",
-	"test timed out",
-	"race detected",
-}
-
-func containsAny(text string, keywords []string) bool {
-	for _, kw := range keywords {
-		if strings.Contains(text, kw) {
-			return true
-		}
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
 	}
-	return false
+	return s[:n] + "..."
 }
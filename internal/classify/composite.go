@@ -0,0 +1,140 @@
+package classify
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/extract"
+	"github.com/okJiang/flaky-test-cleaner/internal/store"
+)
+
+type Member struct {
+	Name       string
+	Classifier Classifier
+	Weight     float64
+}
+
+// Override short-circuits the composite when Member votes Class with at least
+// MinConfidence; later members are not consulted. An empty Class matches any
+// class.
+type Override struct {
+	Member        string
+	Class         Class
+	MinConfidence float64
+}
+
+type Vote struct {
+	Name        string
+	Class       Class
+	Confidence  float64
+	Weight      float64
+	Explanation string
+}
+
+type Composite struct {
+	members   []Member
+	overrides []Override
+}
+
+func NewComposite(members []Member, overrides []Override) *Composite {
+	return &Composite{members: members, overrides: overrides}
+}
+
+func (c *Composite) Classify(ctx context.Context, st store.Store, occ extract.Occurrence) (Result, error) {
+	var votes []Vote
+	var citations []string
+	for _, m := range c.members {
+		res, err := m.Classifier.Classify(ctx, st, occ)
+		if err != nil {
+			if ctx.Err() != nil {
+				return Result{}, ctx.Err()
+			}
+			votes = append(votes, Vote{Name: m.Name, Class: ClassUnknown, Explanation: fmt.Sprintf("abstained: %v", err)})
+			continue
+		}
+		weight := m.Weight
+		if res.Class == ClassUnknown && res.Confidence == 0 {
+			// Zero-confidence unknown means the member had nothing to say.
+			weight = 0
+		}
+		votes = append(votes, Vote{
+			Name:        m.Name,
+			Class:       res.Class,
+			Confidence:  res.Confidence,
+			Weight:      weight,
+			Explanation: res.Explanation,
+		})
+		citations = append(citations, res.Citations...)
+		if o, ok := c.override(m.Name, res); ok {
+			return Result{
+				Class:       res.Class,
+				Confidence:  res.Confidence,
+				Explanation: fmt.Sprintf("%s override (confidence %.2f >= %.2f): %s", o.Member, res.Confidence, o.MinConfidence, res.Explanation),
+				Citations:   citations,
+				Votes:       votes,
+			}, nil
+		}
+	}
+	return combineVotes(votes, citations), nil
+}
+
+func (c *Composite) override(member string, res Result) (Override, bool) {
+	for _, o := range c.overrides {
+		if o.Member == member && (o.Class == "" || o.Class == res.Class) && res.Confidence >= o.MinConfidence {
+			return o, true
+		}
+	}
+	return Override{}, false
+}
+
+// combineVotes picks the class with the highest weighted confidence. Its
+// confidence is that of the strongest vote for it, scaled by its share of
+// all weighted confidence: agreeing votes never lower it, dissenting ones do.
+func combineVotes(votes []Vote, citations []string) Result {
+	scores := map[Class]float64{}
+	strongest := map[Class]float64{}
+	var order []Class
+	total, weight := 0.0, 0.0
+	for _, v := range votes {
+		if v.Weight <= 0 {
+			continue
+		}
+		if _, ok := scores[v.Class]; !ok {
+			order = append(order, v.Class)
+		}
+		scores[v.Class] += v.Weight * v.Confidence
+		strongest[v.Class] = max(strongest[v.Class], v.Confidence)
+		total += v.Weight * v.Confidence
+		weight += v.Weight
+	}
+	if weight == 0 {
+		return Result{Class: ClassUnknown, Confidence: 0, Explanation: "no classifier produced a verdict", Votes: votes}
+	}
+	winner := order[0]
+	for _, cl := range order[1:] {
+		if scores[cl] > scores[winner] {
+			winner = cl
+		}
+	}
+	confidence := 0.0
+	if total > 0 {
+		confidence = strongest[winner] * (scores[winner] / total)
+	}
+	return Result{
+		Class:       winner,
+		Confidence:  confidence,
+		Explanation: fmt.Sprintf("weighted vote of %d classifiers", countWeighted(votes)),
+		Citations:   citations,
+		Votes:       votes,
+	}
+}
+
+func countWeighted(votes []Vote) int {
+	n := 0
+	for _, v := range votes {
+		if v.Weight > 0 {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,91 @@
+package classify
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/extract"
+	"github.com/okJiang/flaky-test-cleaner/internal/store"
+)
+
+type fixedClassifier struct {
+	res   Result
+	err   error
+	calls int
+}
+
+func (f *fixedClassifier) Classify(ctx context.Context, st store.Store, occ extract.Occurrence) (Result, error) {
+	f.calls++
+	return f.res, f.err
+}
+
+func TestCompositeWeightsVotes(t *testing.T) {
+	a := &fixedClassifier{res: Result{Class: ClassUnknown, Confidence: 0.5}}
+	b := &fixedClassifier{res: Result{Class: ClassFlakyTest, Confidence: 0.9}}
+	c := NewComposite([]Member{
+		{Name: "a", Classifier: a, Weight: 1},
+		{Name: "b", Classifier: b, Weight: 3},
+	}, nil)
+	res, err := c.Classify(context.Background(), nil, extract.Occurrence{})
+	if err != nil {
+		t.Fatalf("classify: %v", err)
+	}
+	if res.Class != ClassFlakyTest {
+		t.Fatalf("expected flaky-test, got %s", res.Class)
+	}
+	if want := 0.9 * 2.7 / 3.2; res.Confidence < want-1e-9 || res.Confidence > want+1e-9 {
+		t.Fatalf("expected confidence %.3f, got %.3f", want, res.Confidence)
+	}
+	if len(res.Votes) != 2 {
+		t.Fatalf("expected 2 votes, got %d", len(res.Votes))
+	}
+}
+
+func TestCompositeAgreeingVotesKeepStrongerConfidence(t *testing.T) {
+	heuristic := &fixedClassifier{res: Result{Class: ClassFlakyTest, Confidence: 0.8}}
+	history := &fixedClassifier{res: Result{Class: ClassFlakyTest, Confidence: 0.5}}
+	c := NewComposite([]Member{
+		{Name: "heuristic", Classifier: heuristic, Weight: 1},
+		{Name: "history", Classifier: history, Weight: 1},
+	}, nil)
+	res, err := c.Classify(context.Background(), nil, extract.Occurrence{})
+	if err != nil {
+		t.Fatalf("classify: %v", err)
+	}
+	if res.Class != ClassFlakyTest || res.Confidence < 0.8 {
+		t.Fatalf("expected flaky-test with at least 0.80, got %s %.3f", res.Class, res.Confidence)
+	}
+}
+
+func TestCompositeOverrideShortCircuits(t *testing.T) {
+	a := &fixedClassifier{res: Result{Class: ClassInfraFlake, Confidence: 0.95}}
+	b := &fixedClassifier{res: Result{Class: ClassFlakyTest, Confidence: 0.9}}
+	c := NewComposite([]Member{
+		{Name: "a", Classifier: a, Weight: 1},
+		{Name: "b", Classifier: b, Weight: 1},
+	}, []Override{{Member: "a", Class: ClassInfraFlake, MinConfidence: 0.9}})
+	res, err := c.Classify(context.Background(), nil, extract.Occurrence{})
+	if err != nil {
+		t.Fatalf("classify: %v", err)
+	}
+	if res.Class != ClassInfraFlake || b.calls != 0 {
+		t.Fatalf("expected infra override without consulting b, got %s calls=%d", res.Class, b.calls)
+	}
+}
+
+func TestCompositeMemberErrorAbstains(t *testing.T) {
+	a := &fixedClassifier{err: errors.New("boom")}
+	b := &fixedClassifier{res: Result{Class: ClassFlakyTest, Confidence: 0.8}}
+	c := NewComposite([]Member{
+		{Name: "a", Classifier: a, Weight: 1},
+		{Name: "b", Classifier: b, Weight: 1},
+	}, nil)
+	res, err := c.Classify(context.Background(), nil, extract.Occurrence{})
+	if err != nil {
+		t.Fatalf("classify: %v", err)
+	}
+	if res.Class != ClassFlakyTest || res.Confidence != 0.8 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+}
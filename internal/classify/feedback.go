@@ -0,0 +1,65 @@
+package classify
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/extract"
+	"github.com/okJiang/flaky-test-cleaner/internal/store"
+)
+
+// nearDuplicateConfidence is how much a verdict on another fingerprint of the
+// same test counts; it votes but does not override.
+const nearDuplicateConfidence = 0.7
+
+// Feedback turns maintainer verdicts collected from managed issues into a
+// classification. A verdict on the fingerprint itself is certain; one on a
+// near-duplicate (same test, different fingerprint) is a hint.
+type Feedback struct{}
+
+func NewFeedback() *Feedback { return &Feedback{} }
+
+func (f *Feedback) Classify(ctx context.Context, st store.Store, occ extract.Occurrence) (Result, error) {
+	own, err := st.ListFeedback(ctx, occ.Fingerprint)
+	if err != nil {
+		return Result{}, err
+	}
+	if fb, ok := store.LatestVerdict(own); ok {
+		return verdictResult(fb, 1.0, "maintainer"), nil
+	}
+	if occ.TestName == "" {
+		return Result{Class: ClassUnknown, Confidence: 0, Explanation: "no maintainer feedback"}, nil
+	}
+	related, err := st.ListFeedbackByTest(ctx, occ.Repo, occ.TestName)
+	if err != nil {
+		return Result{}, err
+	}
+	if fb, ok := store.LatestVerdict(related); ok {
+		return verdictResult(fb, nearDuplicateConfidence, "near-duplicate "+shortFingerprint(fb.Fingerprint)), nil
+	}
+	return Result{Class: ClassUnknown, Confidence: 0, Explanation: "no maintainer feedback"}, nil
+}
+
+func verdictResult(fb store.Feedback, confidence float64, scope string) Result {
+	class := ClassUnknown
+	switch fb.Verdict {
+	case store.VerdictFlaky:
+		class = ClassFlakyTest
+	case store.VerdictNotFlaky, store.VerdictRegression:
+		class = ClassLikelyRegression
+	case store.VerdictInfra:
+		class = ClassInfraFlake
+	}
+	return Result{
+		Class:       class,
+		Confidence:  confidence,
+		Explanation: fmt.Sprintf("%s verdict %q by @%s via %s on #%d", scope, fb.Verdict, fb.Actor, fb.Source, fb.IssueNumber),
+	}
+}
+
+func shortFingerprint(fp string) string {
+	if len(fp) <= 12 {
+		return fp
+	}
+	return fp[:12]
+}
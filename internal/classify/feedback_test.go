@@ -0,0 +1,58 @@
+package classify
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/extract"
+	"github.com/okJiang/flaky-test-cleaner/internal/store"
+)
+
+func TestFeedbackUsesLatestVerdict(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewMemory()
+	now := time.Now()
+	_ = st.UpsertFeedback(ctx, store.Feedback{Fingerprint: "fp1", Repo: "o/r", TestName: "TestA", IssueNumber: 7, SourceID: "event:1", Source: "label", Verdict: store.VerdictFlaky, Actor: "alice", CreatedAt: now.Add(-time.Hour)})
+	_ = st.UpsertFeedback(ctx, store.Feedback{Fingerprint: "fp1", Repo: "o/r", TestName: "TestA", IssueNumber: 7, SourceID: "event:2", Source: "state", Verdict: store.VerdictNotFlaky, Actor: "bob", CreatedAt: now})
+
+	res, err := NewFeedback().Classify(ctx, st, extract.Occurrence{Fingerprint: "fp1", Repo: "o/r", TestName: "TestA"})
+	if err != nil {
+		t.Fatalf("classify: %v", err)
+	}
+	if res.Class != ClassLikelyRegression || res.Confidence != 1.0 {
+		t.Fatalf("expected likely-regression 1.0, got %s %.2f", res.Class, res.Confidence)
+	}
+
+	res, err = NewFeedback().Classify(ctx, st, extract.Occurrence{Fingerprint: "fp2", Repo: "o/r", TestName: "TestA"})
+	if err != nil {
+		t.Fatalf("classify: %v", err)
+	}
+	if res.Class != ClassLikelyRegression || res.Confidence != nearDuplicateConfidence {
+		t.Fatalf("expected near-duplicate verdict, got %s %.2f", res.Class, res.Confidence)
+	}
+
+	res, err = NewFeedback().Classify(ctx, st, extract.Occurrence{Fingerprint: "fp3", Repo: "o/r", TestName: "TestB"})
+	if err != nil {
+		t.Fatalf("classify: %v", err)
+	}
+	if res.Class != ClassUnknown || res.Confidence != 0 {
+		t.Fatalf("expected abstain, got %s %.2f", res.Class, res.Confidence)
+	}
+}
+
+func TestCompositeOverrideAnyClass(t *testing.T) {
+	fb := &fixedClassifier{res: Result{Class: ClassFlakyTest, Confidence: 1.0}}
+	other := &fixedClassifier{res: Result{Class: ClassLikelyRegression, Confidence: 0.9}}
+	c := NewComposite([]Member{
+		{Name: "feedback", Classifier: fb, Weight: 1},
+		{Name: "heuristic", Classifier: other, Weight: 1},
+	}, []Override{{Member: "feedback", MinConfidence: 1.0}})
+	res, err := c.Classify(context.Background(), nil, extract.Occurrence{})
+	if err != nil {
+		t.Fatalf("classify: %v", err)
+	}
+	if res.Class != ClassFlakyTest || other.calls != 0 {
+		t.Fatalf("expected feedback override, got %s (heuristic calls=%d)", res.Class, other.calls)
+	}
+}
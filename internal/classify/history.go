@@ -0,0 +1,188 @@
+package classify
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/extract"
+	"github.com/okJiang/flaky-test-cleaner/internal/store"
+)
+
+// minRateExecutions is how many executions are needed before a failure rate
+// is trusted.
+const minRateExecutions = 10
+
+type HistoryOptions struct {
+	Window        time.Duration
+	DefaultBranch string
+	// RegressionRepeats is how many failures on a single commit, and no other,
+	// make a test look like a regression rather than a flake.
+	RegressionRepeats int
+	// Baseline is set when successful runs are sampled into the pass/fail
+	// counts. Without it the counts come from failed jobs only, so a failing
+	// test's failure rate is close to 100% and is not used.
+	Baseline bool
+}
+
+type History struct {
+	opts HistoryOptions
+	now  func() time.Time
+}
+
+func NewHistory(opts HistoryOptions) *History {
+	if opts.Window <= 0 {
+		opts.Window = 30 * 24 * time.Hour
+	}
+	if opts.DefaultBranch == "" {
+		opts.DefaultBranch = "master"
+	}
+	if opts.RegressionRepeats <= 0 {
+		opts.RegressionRepeats = 3
+	}
+	return &History{opts: opts, now: time.Now}
+}
+
+type HistoryStats struct {
+	Failures              int
+	DistinctSHAs          int
+	DefaultBranchFailures int
+	PRFailures            int
+	// RecurrenceRate is the average number of failures per affected commit.
+	RecurrenceRate float64
+	// RerunPasses counts failed jobs that passed on a later attempt of the
+	// same run, i.e. on the same commit.
+	RerunPasses int
+	// Executions and FailureRate come from pass/fail counts, including
+	// baseline samples of successful runs. They stay zero without passes
+	// from baseline samples.
+	Executions  int
+	FailureRate float64
+	Score       float64
+}
+
+func (h *History) Classify(ctx context.Context, st store.Store, occ extract.Occurrence) (Result, error) {
+	if st == nil || occ.TestName == "" {
+		return Result{Class: ClassUnknown, Explanation: "no test history available"}, nil
+	}
+	list, err := st.ListOccurrencesByTest(ctx, occ.Repo, occ.TestName, h.now().Add(-h.opts.Window))
+	if err != nil {
+		return Result{}, err
+	}
+	stats := h.Stats(list)
+	reruns, err := countRerunPasses(ctx, st, list)
+	if err != nil {
+		return Result{}, err
+	}
+	stats.RerunPasses = reruns
+	execs, err := st.GetTestExecutionStats(ctx, occ.Repo, occ.TestName, h.now().Add(-h.opts.Window))
+	if err != nil {
+		return Result{}, err
+	}
+	if h.opts.Baseline && execs.Passed > 0 {
+		stats.Executions = execs.Executions()
+		stats.FailureRate = execs.FailureRate()
+	}
+	return h.verdict(stats), nil
+}
+
+func countRerunPasses(ctx context.Context, st store.Store, list []extract.Occurrence) (int, error) {
+	type runJob struct {
+		run int64
+		job string
+	}
+	attempts := map[int64][]store.JobAttempt{}
+	seen := map[runJob]struct{}{}
+	n := 0
+	for _, occ := range list {
+		key := runJob{occ.RunID, occ.JobName}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		runAttempts, ok := attempts[occ.RunID]
+		if !ok {
+			var err error
+			runAttempts, err = st.ListJobAttempts(ctx, occ.RunID)
+			if err != nil {
+				return 0, err
+			}
+			attempts[occ.RunID] = runAttempts
+		}
+		attempt := occ.RunAttempt
+		if attempt == 0 {
+			attempt = 1
+		}
+		if store.PassedOnRerun(runAttempts, occ.JobName, attempt) != 0 {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (h *History) Stats(list []extract.Occurrence) HistoryStats {
+	type runJob struct{ run, job int64 }
+	seen := map[runJob]struct{}{}
+	shas := map[string]struct{}{}
+	var stats HistoryStats
+	for _, occ := range list {
+		key := runJob{occ.RunID, occ.JobID}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		stats.Failures++
+		if occ.HeadSHA != "" {
+			shas[occ.HeadSHA] = struct{}{}
+		}
+		if occ.Event == "pull_request" || occ.Event == "pull_request_target" {
+			stats.PRFailures++
+		} else if occ.Branch == h.opts.DefaultBranch {
+			stats.DefaultBranchFailures++
+		}
+	}
+	stats.DistinctSHAs = len(shas)
+	if stats.DistinctSHAs > 0 {
+		stats.RecurrenceRate = float64(stats.Failures) / float64(stats.DistinctSHAs)
+	}
+	if stats.Failures > 0 {
+		spread := float64(stats.DistinctSHAs) / float64(stats.Failures)
+		shaFactor := float64(stats.DistinctSHAs-1) / 4
+		if shaFactor > 1 {
+			shaFactor = 1
+		}
+		if shaFactor < 0 {
+			shaFactor = 0
+		}
+		branchFactor := 0.0
+		if stats.DefaultBranchFailures > 0 {
+			branchFactor = 1
+		}
+		stats.Score = 0.5*shaFactor + 0.3*spread + 0.2*branchFactor
+	}
+	return stats
+}
+
+func (h *History) verdict(s HistoryStats) Result {
+	summary := fmt.Sprintf("%d failures on %d commits (%.1f per commit), %d on %s, %d on PRs, %d passed on re-run, flakiness score %.2f",
+		s.Failures, s.DistinctSHAs, s.RecurrenceRate, s.DefaultBranchFailures, h.opts.DefaultBranch, s.PRFailures, s.RerunPasses, s.Score)
+	if s.Executions > 0 {
+		summary += fmt.Sprintf(", failure rate %.1f%% of %d executions", 100*s.FailureRate, s.Executions)
+	}
+	switch {
+	case s.RerunPasses > 0:
+		return Result{Class: ClassFlakyTest, Confidence: 0.9 + 0.05*s.Score, Explanation: "failed then passed on re-run of the same commit: " + summary}
+	case s.Failures < 2:
+		return Result{Class: ClassUnknown, Explanation: "not enough history: " + summary}
+	case s.DistinctSHAs == 1 && s.Failures >= h.opts.RegressionRepeats:
+		return Result{Class: ClassLikelyRegression, Confidence: 0.85, Explanation: "repeated failures on a single commit: " + summary}
+	case s.Executions >= minRateExecutions && s.Failures >= 2 && s.FailureRate < 0.5:
+		return Result{Class: ClassFlakyTest, Confidence: 0.6 + 0.35*s.Score, Explanation: "fails intermittently: " + summary}
+	case s.DistinctSHAs >= 2:
+		return Result{Class: ClassFlakyTest, Confidence: 0.5 + 0.45*s.Score, Explanation: "fails across commits: " + summary}
+	case s.Executions >= minRateExecutions && s.FailureRate >= 0.9:
+		return Result{Class: ClassLikelyRegression, Confidence: 0.8, Explanation: "fails almost every execution: " + summary}
+	default:
+		return Result{Class: ClassUnknown, Explanation: summary}
+	}
+}
@@ -0,0 +1,92 @@
+package classify
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/extract"
+	"github.com/okJiang/flaky-test-cleaner/internal/store"
+)
+
+func seedHistory(t *testing.T, st store.Store, occs ...extract.Occurrence) {
+	t.Helper()
+	for _, occ := range occs {
+		occ.Repo = "tikv/pd"
+		occ.TestName = "TestFoo"
+		occ.OccurredAt = time.Now()
+		if err := st.UpsertOccurrence(context.Background(), occ); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+}
+
+func TestHistoryFlagsFailuresAcrossCommits(t *testing.T) {
+	st := store.NewMemory()
+	seedHistory(t, st,
+		extract.Occurrence{RunID: 1, JobID: 1, HeadSHA: "a", Branch: "master", Event: "push"},
+		extract.Occurrence{RunID: 2, JobID: 2, HeadSHA: "b", Branch: "master", Event: "push"},
+		extract.Occurrence{RunID: 3, JobID: 3, HeadSHA: "c", Branch: "feature", Event: "pull_request"},
+	)
+	res, err := NewHistory(HistoryOptions{}).Classify(context.Background(), st, extract.Occurrence{Repo: "tikv/pd", TestName: "TestFoo"})
+	if err != nil {
+		t.Fatalf("classify: %v", err)
+	}
+	if res.Class != ClassFlakyTest || res.Confidence <= 0.5 {
+		t.Fatalf("expected flaky verdict, got %+v", res)
+	}
+}
+
+func TestHistoryFlagsRepeatedSingleCommitAsRegression(t *testing.T) {
+	st := store.NewMemory()
+	seedHistory(t, st,
+		extract.Occurrence{RunID: 1, JobID: 1, HeadSHA: "a"},
+		extract.Occurrence{RunID: 2, JobID: 2, HeadSHA: "a"},
+		extract.Occurrence{RunID: 3, JobID: 3, HeadSHA: "a"},
+	)
+	res, err := NewHistory(HistoryOptions{}).Classify(context.Background(), st, extract.Occurrence{Repo: "tikv/pd", TestName: "TestFoo"})
+	if err != nil {
+		t.Fatalf("classify: %v", err)
+	}
+	if res.Class != ClassLikelyRegression {
+		t.Fatalf("expected likely-regression, got %+v", res)
+	}
+}
+
+func TestHistoryTreatsPassOnRerunAsFlaky(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewMemory()
+	seedHistory(t, st, extract.Occurrence{RunID: 1, JobID: 10, RunAttempt: 1, JobName: "unit", HeadSHA: "a"})
+	_ = st.UpsertJobAttempt(ctx, store.JobAttempt{RunID: 1, Attempt: 1, JobID: 10, JobName: "unit", Conclusion: "failure"})
+	_ = st.UpsertJobAttempt(ctx, store.JobAttempt{RunID: 1, Attempt: 2, JobID: 11, JobName: "unit", Conclusion: "success"})
+
+	res, err := NewHistory(HistoryOptions{}).Classify(ctx, st, extract.Occurrence{Repo: "tikv/pd", TestName: "TestFoo"})
+	if err != nil {
+		t.Fatalf("classify: %v", err)
+	}
+	if res.Class != ClassFlakyTest || res.Confidence < 0.9 {
+		t.Fatalf("expected strong flaky verdict, got %+v", res)
+	}
+}
+
+func TestHistoryIgnoresFailureRateOfFailedJobsOnly(t *testing.T) {
+	ctx := context.Background()
+	for _, baseline := range []bool{false, true} {
+		st := store.NewMemory()
+		var execs []store.TestExecution
+		for i, sha := range []string{"a", "b", "c", "d", "e", "f"} {
+			seedHistory(t, st, extract.Occurrence{RunID: int64(i + 1), JobID: int64(i + 1), HeadSHA: sha, Branch: "master", Event: "push"})
+			execs = append(execs, store.TestExecution{Repo: "tikv/pd", TestName: "TestFoo", RunID: int64(i + 1), JobID: int64(i + 1), Failed: 2, OccurredAt: time.Now()})
+		}
+		if err := st.RecordTestExecutions(ctx, execs); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+		res, err := NewHistory(HistoryOptions{Baseline: baseline}).Classify(ctx, st, extract.Occurrence{Repo: "tikv/pd", TestName: "TestFoo"})
+		if err != nil {
+			t.Fatalf("classify: %v", err)
+		}
+		if res.Class != ClassFlakyTest {
+			t.Fatalf("baseline=%v: expected flaky verdict from failures across commits, got %+v", baseline, res)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package classify
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/extract"
+	"github.com/okJiang/flaky-test-cleaner/internal/fingerprint"
+	"github.com/okJiang/flaky-test-cleaner/internal/sanitize"
+	"github.com/okJiang/flaky-test-cleaner/internal/store"
+)
+
+const (
+	excerptChunkLines = 20
+	excerptMaxLines   = 400
+)
+
+var ErrInvalidResponse = errors.New("llm returned an invalid classification")
+
+type LLMOptions struct {
+	BaseURL    string
+	APIKey     string
+	Model      string
+	Timeout    time.Duration
+	MaxRetries int
+	// Backoff is the wait before the first retry, doubled for each further
+	// one.
+	Backoff time.Duration
+	// Fallback is used when the endpoint keeps failing or returning malformed
+	// output. Without it those errors are returned to the caller.
+	Fallback Classifier
+}
+
+type LLMClassifier struct {
+	opts LLMOptions
+	http *http.Client
+}
+
+func NewLLMClassifier(opts LLMOptions) *LLMClassifier {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 60 * time.Second
+	}
+	if opts.MaxRetries < 0 {
+		opts.MaxRetries = 0
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = time.Second
+	}
+	return &LLMClassifier{opts: opts, http: &http.Client{Timeout: opts.Timeout}}
+}
+
+type EvidencePack struct {
+	Repo           string           `json:"repo"`
+	Workflow       string           `json:"workflow"`
+	RunURL         string           `json:"run_url"`
+	CommitSHA      string           `json:"commit_sha"`
+	JobName        string           `json:"job_name"`
+	RunnerOS       string           `json:"runner_os"`
+	Framework      string           `json:"test_framework"`
+	TestName       string           `json:"test_name"`
+	ErrorSignature string           `json:"error_signature"`
+	Excerpts       []ExcerptSnippet `json:"excerpts"`
+}
+
+type ExcerptSnippet struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+}
+
+type llmVerdict struct {
+	Class       Class    `json:"class"`
+	Confidence  float64  `json:"confidence"`
+	Explanation string   `json:"explanation"`
+	Citations   []string `json:"citations"`
+}
+
+func BuildEvidencePack(occ extract.Occurrence) EvidencePack {
+	pack := EvidencePack{
+		Repo:           occ.Repo,
+		Workflow:       occ.Workflow,
+		RunURL:         occ.RunURL,
+		CommitSHA:      occ.HeadSHA,
+		JobName:        sanitize.Scrub(occ.JobName),
+		RunnerOS:       occ.RunnerOS,
+		Framework:      occ.Framework,
+		TestName:       sanitize.Scrub(occ.TestName),
+		ErrorSignature: sanitize.Scrub(occ.ErrorSignature),
+	}
+	lines := strings.Split(sanitize.Scrub(occ.Excerpt), "\n")
+	if len(lines) > excerptMaxLines {
+		lines = lines[:excerptMaxLines]
+	}
+	for i := 0; i < len(lines); i += excerptChunkLines {
+		end := i + excerptChunkLines
+		if end > len(lines) {
+			end = len(lines)
+		}
+		text := strings.TrimSpace(strings.Join(lines[i:end], "\n"))
+		if text == "" {
+			continue
+		}
+		pack.Excerpts = append(pack.Excerpts, ExcerptSnippet{ID: fmt.Sprintf("E%d", len(pack.Excerpts)+1), Text: text})
+	}
+	return pack
+}
+
+var reLogTimestamp = regexp.MustCompile(`(?m)^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d(?:\.\d+)?Z ?`)
+
+// Hash is the classification cache key of the pack for model: the test, and
+// the signature and excerpts normalized the way fingerprints are, so that
+// timestamps, durations and addresses of a run do not change it. Run
+// metadata such as the URL and commit is left out, so a fingerprint failing
+// the same way in another run reuses the verdict.
+func (p EvidencePack) Hash(model string) string {
+	excerpts := make([]string, 0, len(p.Excerpts))
+	for _, ex := range p.Excerpts {
+		excerpts = append(excerpts, fingerprint.NormalizeErrorSignature(reLogTimestamp.ReplaceAllString(ex.Text, "")))
+	}
+	b, _ := json.Marshal(struct {
+		Model          string   `json:"model"`
+		TestName       string   `json:"test_name"`
+		ErrorSignature string   `json:"error_signature"`
+		Excerpts       []string `json:"excerpts"`
+	}{model, p.TestName, fingerprint.NormalizeErrorSignature(p.ErrorSignature), excerpts})
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+func (l *LLMClassifier) Classify(ctx context.Context, st store.Store, occ extract.Occurrence) (Result, error) {
+	pack := BuildEvidencePack(occ)
+	hash := pack.Hash(l.opts.Model)
+	if st != nil && occ.Fingerprint != "" {
+		cached, err := st.GetClassificationCache(ctx, occ.Fingerprint, hash)
+		if err != nil {
+			return Result{}, err
+		}
+		if cached != nil {
+			if v, err := parseVerdict(cached.Payload, pack); err == nil {
+				return v.result("cached"), nil
+			}
+		}
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= l.opts.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return Result{}, ctx.Err()
+			case <-time.After(l.opts.Backoff << (attempt - 1)):
+			}
+		}
+		content, err := l.complete(ctx, pack)
+		if err != nil {
+			lastErr = err
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		v, err := parseVerdict(content, pack)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if st != nil && occ.Fingerprint != "" {
+			payload, _ := json.Marshal(v)
+			if err := st.PutClassificationCache(ctx, store.ClassificationCacheEntry{
+				Fingerprint:  occ.Fingerprint,
+				EvidenceHash: hash,
+				Model:        l.opts.Model,
+				Payload:      string(payload),
+			}); err != nil {
+				return Result{}, err
+			}
+		}
+		return v.result(""), nil
+	}
+	if l.opts.Fallback != nil {
+		res, err := l.opts.Fallback.Classify(ctx, st, occ)
+		if err != nil {
+			return Result{}, err
+		}
+		res.Explanation = fmt.Sprintf("%s (llm unavailable: %v)", res.Explanation, lastErr)
+		return res, nil
+	}
+	return Result{}, lastErr
+}
+
+func (v llmVerdict) result(source string) Result {
+	explanation := v.Explanation
+	if source != "" {
+		explanation = fmt.Sprintf("%s (%s)", explanation, source)
+	}
+	return Result{
+		Class:       v.Class,
+		Confidence:  v.Confidence,
+		Explanation: explanation,
+		Citations:   v.Citations,
+	}
+}
+
+func parseVerdict(content string, pack EvidencePack) (llmVerdict, error) {
+	dec := json.NewDecoder(strings.NewReader(strings.TrimSpace(content)))
+	dec.DisallowUnknownFields()
+	var v llmVerdict
+	if err := dec.Decode(&v); err != nil {
+		return llmVerdict{}, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	if dec.More() {
+		return llmVerdict{}, fmt.Errorf("%w: trailing data", ErrInvalidResponse)
+	}
+	switch v.Class {
+	case ClassFlakyTest, ClassInfraFlake, ClassLikelyRegression, ClassUnknown:
+	default:
+		return llmVerdict{}, fmt.Errorf("%w: unknown class %q", ErrInvalidResponse, v.Class)
+	}
+	if v.Confidence < 0 || v.Confidence > 1 {
+		return llmVerdict{}, fmt.Errorf("%w: confidence %v out of range", ErrInvalidResponse, v.Confidence)
+	}
+	if strings.TrimSpace(v.Explanation) == "" {
+		return llmVerdict{}, fmt.Errorf("%w: empty explanation", ErrInvalidResponse)
+	}
+	ids := map[string]struct{}{}
+	for _, ex := range pack.Excerpts {
+		ids[ex.ID] = struct{}{}
+	}
+	for _, id := range v.Citations {
+		if _, ok := ids[id]; !ok {
+			return llmVerdict{}, fmt.Errorf("%w: unknown excerpt id %q", ErrInvalidResponse, id)
+		}
+	}
+	return v, nil
+}
+
+const llmSystemPrompt = `You classify CI test failures.
+Reply with a single JSON object and nothing else:
+{"class": "flaky-test" | "infra-flake" | "likely-regression" | "unknown",
+ "confidence": number between 0 and 1,
+ "explanation": why you chose the class and what would make you more certain,
+ "citations": ids of the excerpts that support the decision, e.g. ["E1"]}
+infra-flake: network, downloads, runner loss, permissions or quota.
+likely-regression: deterministic compile errors, missing symbols, consistent assertion failures.
+flaky-test: timing, data races, randomness, order dependence.`
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+func (l *LLMClassifier) complete(ctx context.Context, pack EvidencePack) (string, error) {
+	evidence, err := json.Marshal(pack)
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(map[string]any{
+		"model":       l.opts.Model,
+		"temperature": 0,
+		"messages": []chatMessage{
+			{Role: "system", Content: llmSystemPrompt},
+			{Role: "user", Content: string(evidence)},
+		},
+		"response_format": map[string]string{"type": "json_object"},
+	})
+	if err != nil {
+		return "", err
+	}
+	url := strings.TrimRight(l.opts.BaseURL, "/") + "/chat/completions"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if l.opts.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+l.opts.APIKey)
+	}
+	resp, err := l.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("llm api error: %d %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var res struct {
+		Choices []struct {
+			Message chatMessage `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	if len(res.Choices) == 0 {
+		return "", fmt.Errorf("%w: no choices", ErrInvalidResponse)
+	}
+	return res.Choices[0].Message.Content, nil
+}
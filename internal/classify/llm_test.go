@@ -0,0 +1,89 @@
+package classify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/extract"
+	"github.com/okJiang/flaky-test-cleaner/internal/store"
+)
+
+func mockChatServer(t *testing.T, replies ...string) (*httptest.Server, *int) {
+	t.Helper()
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		reply := replies[len(replies)-1]
+		if calls < len(replies) {
+			reply = replies[calls]
+		}
+		calls++
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": map[string]string{"role": "assistant", "content": reply}}},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestLLMClassifierRetriesAndCaches(t *testing.T) {
+	srv, calls := mockChatServer(t,
+		"not json",
+		`{"class":"flaky-test","confidence":0.9,"explanation":"race","citations":["E1"]}`,
+	)
+	llm := NewLLMClassifier(LLMOptions{BaseURL: srv.URL, Model: "test", MaxRetries: 1, Backoff: time.Millisecond})
+	st := store.NewMemory()
+	occ := extract.Occurrence{Fingerprint: "fp", TestName: "TestFoo", ErrorSignature: "DATA RACE", Excerpt: "2024-01-01T00:00:00.1234567Z WARNING: DATA RACE\nread at 0x00c000123456 after 1.2s"}
+
+	res, err := llm.Classify(context.Background(), st, occ)
+	if err != nil {
+		t.Fatalf("classify: %v", err)
+	}
+	if res.Class != ClassFlakyTest || res.Confidence != 0.9 || len(res.Citations) != 1 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if *calls != 2 {
+		t.Fatalf("expected 2 calls, got %d", *calls)
+	}
+
+	occ.RunURL, occ.HeadSHA = "https://github.com/o/r/actions/runs/2", "def456"
+	occ.Excerpt = "2024-02-03T10:11:12.7654321Z WARNING: DATA RACE\nread at 0x00c000abcdef after 3.4s"
+	if _, err := llm.Classify(context.Background(), st, occ); err != nil {
+		t.Fatalf("cached classify: %v", err)
+	}
+	if *calls != 2 {
+		t.Fatalf("expected cache hit, got %d calls", *calls)
+	}
+
+	other := NewLLMClassifier(LLMOptions{BaseURL: srv.URL, Model: "other"})
+	if _, err := other.Classify(context.Background(), st, occ); err != nil {
+		t.Fatalf("other model classify: %v", err)
+	}
+	if *calls != 3 {
+		t.Fatalf("expected a new model to miss the cache, got %d calls", *calls)
+	}
+}
+
+func TestLLMClassifierRejectsInvalidVerdict(t *testing.T) {
+	srv, _ := mockChatServer(t, `{"class":"flaky-test","confidence":0.9,"explanation":"x","citations":["E9"]}`)
+	llm := NewLLMClassifier(LLMOptions{BaseURL: srv.URL, Model: "test"})
+	occ := extract.Occurrence{Fingerprint: "fp", Excerpt: "panic: boom"}
+	if _, err := llm.Classify(context.Background(), store.NewMemory(), occ); err == nil {
+		t.Fatalf("expected error for unknown citation")
+	}
+
+	llm = NewLLMClassifier(LLMOptions{BaseURL: srv.URL, Model: "test", Fallback: NewHeuristic()})
+	res, err := llm.Classify(context.Background(), store.NewMemory(), occ)
+	if err != nil {
+		t.Fatalf("fallback classify: %v", err)
+	}
+	if res.Class != ClassFlakyTest {
+		t.Fatalf("expected heuristic fallback, got %+v", res)
+	}
+}
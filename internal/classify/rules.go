@@ -0,0 +1,194 @@
+package classify
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/extract"
+)
+
+//go:embed default_rules.json
+var defaultRulesJSON []byte
+
+// Rule fields select which part of an occurrence a pattern is matched against.
+const (
+	FieldSignature = "signature"
+	FieldExcerpt   = "excerpt"
+	FieldJob       = "job"
+	FieldStep      = "step"
+	FieldTest      = "test"
+	FieldText      = "text"
+)
+
+type Rule struct {
+	Name       string  `json:"name"`
+	Field      string  `json:"field"`
+	Pattern    string  `json:"pattern"`
+	Class      Class   `json:"class"`
+	Confidence float64 `json:"confidence"`
+	Priority   int     `json:"priority"`
+
+	re *regexp.Regexp
+}
+
+type RuleHit struct {
+	Rule  Rule
+	Match string
+}
+
+type RuleSet struct {
+	Rules []Rule
+}
+
+// RuleSource hands out the rule set to use for the next classification.
+type RuleSource interface {
+	Current() *RuleSet
+}
+
+func (rs *RuleSet) Current() *RuleSet { return rs }
+
+func DefaultRules() *RuleSet {
+	rs, err := ParseRules(defaultRulesJSON)
+	if err != nil {
+		panic(fmt.Sprintf("invalid embedded rules: %v", err))
+	}
+	return rs
+}
+
+func ParseRules(data []byte) (*RuleSet, error) {
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("parse rules: %w", err)
+	}
+	for i := range rules {
+		r := &rules[i]
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule-%d", i+1)
+		}
+		if r.Field == "" {
+			r.Field = FieldText
+		}
+		switch r.Field {
+		case FieldSignature, FieldExcerpt, FieldJob, FieldStep, FieldTest, FieldText:
+		default:
+			return nil, fmt.Errorf("rule %s: unknown field %q", r.Name, r.Field)
+		}
+		switch r.Class {
+		case ClassFlakyTest, ClassInfraFlake, ClassLikelyRegression, ClassUnknown:
+		default:
+			return nil, fmt.Errorf("rule %s: unknown class %q", r.Name, r.Class)
+		}
+		if r.Confidence < 0 || r.Confidence > 1 {
+			return nil, fmt.Errorf("rule %s: confidence %v out of range", r.Name, r.Confidence)
+		}
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", r.Name, err)
+		}
+		r.re = re
+	}
+	return &RuleSet{Rules: rules}, nil
+}
+
+func LoadRules(path string) (*RuleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRules(data)
+}
+
+// Match returns every rule hit, best first: higher priority, then higher
+// confidence, then file order.
+func (rs *RuleSet) Match(occ extract.Occurrence) []RuleHit {
+	var hits []RuleHit
+	for _, r := range rs.Rules {
+		if m := r.re.FindString(fieldValue(occ, r.Field)); m != "" {
+			hits = append(hits, RuleHit{Rule: r, Match: m})
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Rule.Priority != hits[j].Rule.Priority {
+			return hits[i].Rule.Priority > hits[j].Rule.Priority
+		}
+		return hits[i].Rule.Confidence > hits[j].Rule.Confidence
+	})
+	return hits
+}
+
+func fieldValue(occ extract.Occurrence, field string) string {
+	switch field {
+	case FieldSignature:
+		return occ.ErrorSignature
+	case FieldExcerpt:
+		return occ.Excerpt
+	case FieldJob:
+		return occ.JobName
+	case FieldStep:
+		return occ.Step
+	case FieldTest:
+		return occ.TestName
+	default:
+		return occ.ErrorSignature + "\n" + occ.Excerpt
+	}
+}
+
+// RuleWatcher reloads a rules file when it changes on disk. A broken file
+// keeps the previous rules in effect.
+type RuleWatcher struct {
+	path string
+
+	mu      sync.Mutex
+	current *RuleSet
+	modTime time.Time
+}
+
+func NewRuleWatcher(path string) (*RuleWatcher, error) {
+	w := &RuleWatcher{path: path, current: DefaultRules()}
+	if path == "" {
+		return w, nil
+	}
+	if _, err := w.Reload(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *RuleWatcher) Current() *RuleSet {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.current
+}
+
+// Reload re-reads the rules file if its modification time changed and reports
+// whether new rules were installed.
+func (w *RuleWatcher) Reload() (bool, error) {
+	if w.path == "" {
+		return false, nil
+	}
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return false, err
+	}
+	w.mu.Lock()
+	unchanged := info.ModTime().Equal(w.modTime)
+	w.mu.Unlock()
+	if unchanged {
+		return false, nil
+	}
+	rs, err := LoadRules(w.path)
+	if err != nil {
+		return false, err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.current = rs
+	w.modTime = info.ModTime()
+	return true, nil
+}
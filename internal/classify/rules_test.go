@@ -0,0 +1,86 @@
+package classify
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/extract"
+)
+
+func TestRulesPriorityAndField(t *testing.T) {
+	rs, err := ParseRules([]byte(`[
+		{"name": "timeout", "field": "text", "pattern": "timeout", "class": "flaky-test", "confidence": 0.7, "priority": 1},
+		{"name": "assert", "field": "signature", "pattern": "expected .* got", "class": "likely-regression", "confidence": 0.9, "priority": 5},
+		{"name": "job", "field": "job", "pattern": "^lint$", "class": "infra-flake", "confidence": 0.9, "priority": 9}
+	]`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	h := NewHeuristicWithRules(rs)
+	res, err := h.Classify(context.Background(), nil, extract.Occurrence{
+		JobName:        "unit",
+		ErrorSignature: "expected 1 got 2",
+		Excerpt:        "context deadline: timeout",
+	})
+	if err != nil {
+		t.Fatalf("classify: %v", err)
+	}
+	if res.Class != ClassLikelyRegression {
+		t.Fatalf("expected higher priority regression rule to win, got %+v", res)
+	}
+	if !strings.Contains(res.Explanation, "rule assert") || !strings.Contains(res.Explanation, "timeout→flaky-test") {
+		t.Fatalf("expected explanation to list hits, got %q", res.Explanation)
+	}
+}
+
+func TestParseRulesRejectsInvalid(t *testing.T) {
+	for _, data := range []string{
+		`[{"pattern": "(", "class": "flaky-test"}]`,
+		`[{"pattern": "x", "class": "bogus"}]`,
+		`[{"pattern": "x", "class": "flaky-test", "field": "nope"}]`,
+		`[{"pattern": "x", "class": "flaky-test", "confidence": 2}]`,
+	} {
+		if _, err := ParseRules([]byte(data)); err == nil {
+			t.Fatalf("expected error for %s", data)
+		}
+	}
+}
+
+func TestRuleWatcherReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	write := func(content string, mod time.Time) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	base := time.Now().Add(-time.Hour)
+	write(`[{"name": "a", "pattern": "a", "class": "flaky-test"}]`, base)
+	w, err := NewRuleWatcher(path)
+	if err != nil {
+		t.Fatalf("watcher: %v", err)
+	}
+
+	write(`[{"name": "b", "pattern": "b", "class": "flaky-test"}]`, base.Add(time.Minute))
+	if reloaded, err := w.Reload(); err != nil || !reloaded {
+		t.Fatalf("expected reload, got %v %v", reloaded, err)
+	}
+	if got := w.Current().Rules[0].Name; got != "b" {
+		t.Fatalf("expected rule b, got %s", got)
+	}
+
+	write(`not json`, base.Add(2*time.Minute))
+	if _, err := w.Reload(); err == nil {
+		t.Fatalf("expected reload error")
+	}
+	if got := w.Current().Rules[0].Name; got != "b" {
+		t.Fatalf("expected previous rules kept, got %s", got)
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"errors"
 	"flag"
+	"fmt"
 	"os"
 	"strconv"
 	"strings"
@@ -15,14 +16,58 @@ type Config struct {
 
 	GitHubReadToken  string
 	GitHubIssueToken string
+	BotLogin         string
 
-	WorkflowName string
-	MaxRuns      int
-	MaxJobs      int
+	WorkflowName  string
+	DefaultBranch string
+	MaxRuns       int
+	MaxJobs       int
+	HistoryWindow time.Duration
+
+	BaselineEnabled bool
+	BaselineRuns    int
 
 	DryRun bool
+	// DryRunDir receives a subdirectory per dry-run scan with every planned
+	// issue change; empty means dry-run only logs.
+	DryRunDir         string
+	MinUpdateInterval time.Duration
+	QuietPeriod       time.Duration
+	SpikeFactor       float64
+	CommentInterval   time.Duration
+	AutoCloseDays     int
+	DigestPeriod      time.Duration
+	DigestTop         int
+	HumanIssueQuery   string
+	AutoAssign        bool
+	TemplateDir       string
+	RepoProfile       string
 
 	ConfidenceThreshold float64
+	// NeedsTriage opens needs-triage issues for results the default policy
+	// would only record.
+	NeedsTriage bool
+	// MinOccurrences, MinDistinctSHAs, EvidenceWindow and
+	// RequireDefaultBranch gate the creation of issues.
+	MinOccurrences       int
+	MinDistinctSHAs      int
+	EvidenceWindow       time.Duration
+	RequireDefaultBranch bool
+	RulesFile            string
+
+	LLMEnabled    bool
+	LLMBaseURL    string
+	LLMAPIKey     string
+	LLMModel      string
+	LLMMaxRetries int
+	LLMTimeout    time.Duration
+
+	HeuristicWeight              float64
+	LLMWeight                    float64
+	HistoryWeight                float64
+	FeedbackWeight               float64
+	InfraOverrideConfidence      float64
+	RegressionOverrideConfidence float64
 
 	TiDBEnabled    bool
 	TiDBHost       string
@@ -34,23 +79,70 @@ type Config struct {
 
 	RequestTimeout time.Duration
 	RunInterval    time.Duration
+
+	EvalCorpus         string
+	EvalUpdateBaseline bool
+
+	Command string
+	Args    []string
 }
 
 func FromEnvAndFlags(args []string) (Config, error) {
 	fs := flag.NewFlagSet("flaky-test-cleaner", flag.ContinueOnError)
 
 	var cfg Config
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cfg.Command = args[0]
+		args = args[1:]
+	}
 	cfg.GitHubOwner = envOr("FTC_GITHUB_OWNER", "tikv")
 	cfg.GitHubRepo = envOr("FTC_GITHUB_REPO", "pd")
 	cfg.GitHubReadToken = os.Getenv("FTC_GITHUB_READ_TOKEN")
 	cfg.GitHubIssueToken = os.Getenv("FTC_GITHUB_ISSUE_TOKEN")
+	cfg.BotLogin = os.Getenv("FTC_BOT_LOGIN")
 
 	cfg.WorkflowName = envOr("FTC_WORKFLOW_NAME", "PD Test")
+	cfg.DefaultBranch = envOr("FTC_DEFAULT_BRANCH", "master")
 	cfg.MaxRuns = envIntOr("FTC_MAX_RUNS", 20)
 	cfg.MaxJobs = envIntOr("FTC_MAX_JOBS", 50)
+	cfg.BaselineEnabled = envBoolOr("FTC_BASELINE_ENABLED", false)
+	cfg.BaselineRuns = envIntOr("FTC_BASELINE_RUNS", 10)
 
 	cfg.DryRun = envBoolOr("FTC_DRY_RUN", true)
+	cfg.DryRunDir = os.Getenv("FTC_DRY_RUN_DIR")
+	cfg.MinUpdateInterval = envDurationOr("FTC_MIN_ISSUE_UPDATE_INTERVAL", 6*time.Hour)
+	cfg.QuietPeriod = envDurationOr("FTC_QUIET_PERIOD", 7*24*time.Hour)
+	cfg.SpikeFactor = envFloatOr("FTC_SPIKE_FACTOR", 3)
+	cfg.CommentInterval = envDurationOr("FTC_COMMENT_INTERVAL", 24*time.Hour)
+	cfg.AutoCloseDays = envIntOr("FTC_AUTO_CLOSE_DAYS", 0)
+	cfg.DigestPeriod = envDurationOr("FTC_DIGEST_PERIOD", 7*24*time.Hour)
+	cfg.DigestTop = envIntOr("FTC_DIGEST_TOP", 10)
+	cfg.HumanIssueQuery = os.Getenv("FTC_HUMAN_ISSUE_QUERY")
+	cfg.AutoAssign = envBoolOr("FTC_AUTO_ASSIGN", false)
+	cfg.TemplateDir = os.Getenv("FTC_TEMPLATE_DIR")
+	cfg.RepoProfile = os.Getenv("FTC_REPO_PROFILE")
 	cfg.ConfidenceThreshold = envFloatOr("FTC_CONFIDENCE_THRESHOLD", 0.75)
+	cfg.NeedsTriage = envBoolOr("FTC_NEEDS_TRIAGE", false)
+	cfg.MinOccurrences = envIntOr("FTC_MIN_OCCURRENCES", 1)
+	cfg.MinDistinctSHAs = envIntOr("FTC_MIN_DISTINCT_SHAS", 1)
+	cfg.EvidenceWindow = envDurationOr("FTC_EVIDENCE_WINDOW", 0)
+	cfg.RequireDefaultBranch = envBoolOr("FTC_REQUIRE_DEFAULT_BRANCH", false)
+	cfg.RulesFile = os.Getenv("FTC_RULES_FILE")
+
+	cfg.LLMEnabled = envBoolOr("FTC_LLM_ENABLED", false)
+	cfg.LLMBaseURL = envOr("FTC_LLM_BASE_URL", "https://api.openai.com/v1")
+	cfg.LLMAPIKey = os.Getenv("FTC_LLM_API_KEY")
+	cfg.LLMModel = envOr("FTC_LLM_MODEL", "gpt-4o-mini")
+	cfg.LLMMaxRetries = envIntOr("FTC_LLM_MAX_RETRIES", 2)
+	cfg.LLMTimeout = envDurationOr("FTC_LLM_TIMEOUT", 60*time.Second)
+
+	cfg.HeuristicWeight = envFloatOr("FTC_HEURISTIC_WEIGHT", 1.0)
+	cfg.LLMWeight = envFloatOr("FTC_LLM_WEIGHT", 1.0)
+	cfg.HistoryWeight = envFloatOr("FTC_HISTORY_WEIGHT", 1.0)
+	cfg.FeedbackWeight = envFloatOr("FTC_FEEDBACK_WEIGHT", 1.0)
+	cfg.HistoryWindow = envDurationOr("FTC_HISTORY_WINDOW", 30*24*time.Hour)
+	cfg.InfraOverrideConfidence = envFloatOr("FTC_INFRA_OVERRIDE_CONFIDENCE", 0.9)
+	cfg.RegressionOverrideConfidence = envFloatOr("FTC_REGRESSION_OVERRIDE_CONFIDENCE", 0.85)
 
 	cfg.TiDBEnabled = envBoolOr("FTC_TIDB_ENABLED", false)
 	cfg.TiDBHost = os.Getenv("TIDB_HOST")
@@ -60,6 +152,8 @@ func FromEnvAndFlags(args []string) (Config, error) {
 	cfg.TiDBDatabase = envOr("TIDB_DATABASE", "flaky_test_cleaner")
 	cfg.TiDBCACertPath = os.Getenv("TIDB_CA_CERT_PATH")
 
+	cfg.EvalCorpus = envOr("FTC_EVAL_CORPUS", "internal/eval/testdata/corpus")
+
 	cfg.RequestTimeout = envDurationOr("FTC_REQUEST_TIMEOUT", 30*time.Second)
 	cfg.RunInterval = envDurationOr("FTC_RUN_INTERVAL", 0)
 
@@ -68,23 +162,56 @@ func FromEnvAndFlags(args []string) (Config, error) {
 	fs.StringVar(&cfg.WorkflowName, "workflow", cfg.WorkflowName, "Workflow name to scan")
 	fs.IntVar(&cfg.MaxRuns, "max-runs", cfg.MaxRuns, "Max failed runs to scan")
 	fs.IntVar(&cfg.MaxJobs, "max-jobs", cfg.MaxJobs, "Max jobs per run to scan")
+	fs.BoolVar(&cfg.BaselineEnabled, "baseline", cfg.BaselineEnabled, "Also sample successful runs to compute per-test failure rates")
+	fs.IntVar(&cfg.BaselineRuns, "baseline-runs", cfg.BaselineRuns, "Max successful runs to sample in baseline mode")
 	fs.BoolVar(&cfg.DryRun, "dry-run", cfg.DryRun, "Do not write to GitHub (issue create/update); still writes to TiDB if enabled")
-	fs.Float64Var(&cfg.ConfidenceThreshold, "confidence-threshold", cfg.ConfidenceThreshold, "Classifier threshold to label as flaky")
+	fs.StringVar(&cfg.DryRunDir, "dry-run-dir", cfg.DryRunDir, "In dry-run, write planned issue changes and diffs (Markdown/JSON) under this directory")
+	fs.BoolVar(&cfg.AutoAssign, "auto-assign", cfg.AutoAssign, "Assign suggested owners who are collaborators to unassigned issues")
+	fs.StringVar(&cfg.TemplateDir, "templates", cfg.TemplateDir, "Directory of issue templates overriding the built-in ones")
+	fs.StringVar(&cfg.RepoProfile, "profile", cfg.RepoProfile, "Repository profile (JSON), e.g. reproduction command settings")
+	fs.Float64Var(&cfg.ConfidenceThreshold, "confidence-threshold", cfg.ConfidenceThreshold, "Minimum confidence for a flaky-test issue under the default policy")
+	fs.BoolVar(&cfg.NeedsTriage, "needs-triage", cfg.NeedsTriage, "Open needs-triage issues for low-confidence and unknown results instead of only recording them")
+	fs.IntVar(&cfg.MinOccurrences, "min-occurrences", cfg.MinOccurrences, "Occurrences required before opening an issue")
+	fs.IntVar(&cfg.MinDistinctSHAs, "min-commits", cfg.MinDistinctSHAs, "Distinct failing commits required before opening an issue")
+	fs.DurationVar(&cfg.EvidenceWindow, "evidence-window", cfg.EvidenceWindow, "Only count occurrences this recent toward the issue thresholds (0 for all)")
+	fs.BoolVar(&cfg.RequireDefaultBranch, "require-default-branch", cfg.RequireDefaultBranch, "Require a failure on the default branch before opening an issue")
+	fs.StringVar(&cfg.RulesFile, "rules", cfg.RulesFile, "Heuristic rules file (JSON); reloaded between scans")
+	fs.BoolVar(&cfg.LLMEnabled, "llm", cfg.LLMEnabled, "Enable the LLM classifier (OpenAI-compatible chat endpoint)")
 	fs.BoolVar(&cfg.TiDBEnabled, "tidb", cfg.TiDBEnabled, "Enable TiDB state store")
 	fs.DurationVar(&cfg.RunInterval, "interval", cfg.RunInterval, "Interval to run continuously (0 for run once)")
+	fs.StringVar(&cfg.EvalCorpus, "corpus", cfg.EvalCorpus, "Labeled corpus directory for the eval command")
+	fs.BoolVar(&cfg.EvalUpdateBaseline, "update-baseline", cfg.EvalUpdateBaseline, "Overwrite the corpus baseline with the eval result")
 	if err := fs.Parse(args); err != nil {
 		return Config{}, err
 	}
+	cfg.Args = fs.Args()
 
 	if cfg.GitHubOwner == "" || cfg.GitHubRepo == "" {
 		return Config{}, errors.New("owner/repo must be set")
 	}
-	if cfg.GitHubReadToken == "" {
+	if cfg.ReadsGitHub() && cfg.GitHubReadToken == "" {
 		return Config{}, errors.New("FTC_GITHUB_READ_TOKEN is required")
 	}
-	if !cfg.DryRun && cfg.GitHubIssueToken == "" {
+	if cfg.WritesGitHub() && !cfg.DryRun && cfg.GitHubIssueToken == "" {
 		return Config{}, errors.New("FTC_GITHUB_ISSUE_TOKEN is required unless --dry-run")
 	}
+	if cfg.LLMEnabled && (strings.TrimSpace(cfg.LLMBaseURL) == "" || strings.TrimSpace(cfg.LLMModel) == "") {
+		return Config{}, errors.New("LLM enabled but FTC_LLM_BASE_URL/FTC_LLM_MODEL not set")
+	}
+	if cfg.MinOccurrences < 0 || cfg.MinDistinctSHAs < 0 || cfg.EvidenceWindow < 0 {
+		return Config{}, errors.New("issue evidence thresholds must not be negative")
+	}
+	if cfg.DigestPeriod <= 0 {
+		return Config{}, errors.New("FTC_DIGEST_PERIOD must be positive")
+	}
+	if cfg.TemplateDir != "" {
+		if fi, err := os.Stat(cfg.TemplateDir); err != nil || !fi.IsDir() {
+			return Config{}, fmt.Errorf("template dir %q is not a directory", cfg.TemplateDir)
+		}
+	}
+	if cfg.NeedsStore() && !cfg.TiDBEnabled {
+		return Config{}, fmt.Errorf("%s requires --tidb: it reads what earlier scans stored", cfg.Command)
+	}
 	if cfg.TiDBEnabled {
 		if cfg.TiDBHost == "" || cfg.TiDBUser == "" || cfg.TiDBPassword == "" {
 			return Config{}, errors.New("TiDB enabled but TIDB_HOST/TIDB_USER/TIDB_PASSWORD not set")
@@ -97,6 +224,31 @@ func FromEnvAndFlags(args []string) (Config, error) {
 	return cfg, nil
 }
 
+func (c Config) ReadsGitHub() bool {
+	switch c.Command {
+	case "", "scan":
+		return true
+	}
+	return false
+}
+
+// NeedsStore reports whether the command only works on a persistent store.
+func (c Config) NeedsStore() bool {
+	switch c.Command {
+	case "merge", "split", "aliases", "digest", "candidates":
+		return true
+	}
+	return false
+}
+
+func (c Config) WritesGitHub() bool {
+	switch c.Command {
+	case "", "scan", "merge", "digest":
+		return true
+	}
+	return false
+}
+
 func envOr(key, def string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
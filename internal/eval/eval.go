@@ -0,0 +1,334 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/classify"
+	"github.com/okJiang/flaky-test-cleaner/internal/extract"
+	"github.com/okJiang/flaky-test-cleaner/internal/fingerprint"
+	"github.com/okJiang/flaky-test-cleaner/internal/sanitize"
+	"github.com/okJiang/flaky-test-cleaner/internal/store"
+)
+
+// A corpus is a directory of cases; each case directory holds the sanitized
+// job log (job.log) and its labels (case.json).
+const (
+	caseFile = "case.json"
+	logFile  = "job.log"
+)
+
+var ErrRegressed = errors.New("evaluation regressed against baseline")
+
+type ExpectedOccurrence struct {
+	TestName          string         `json:"test_name"`
+	SignatureContains string         `json:"signature_contains,omitempty"`
+	Class             classify.Class `json:"class"`
+	Group             string         `json:"group"`
+}
+
+type Case struct {
+	Name     string               `json:"-"`
+	Log      string               `json:"-"`
+	JobName  string               `json:"job_name"`
+	RunnerOS string               `json:"runner_os"`
+	HeadSHA  string               `json:"head_sha"`
+	Branch   string               `json:"branch"`
+	Event    string               `json:"event"`
+	Expected []ExpectedOccurrence `json:"expected"`
+}
+
+func LoadCorpus(dir string) ([]Case, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var cases []Case
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		meta, err := os.ReadFile(filepath.Join(dir, e.Name(), caseFile))
+		if err != nil {
+			return nil, err
+		}
+		var c Case
+		if err := json.Unmarshal(meta, &c); err != nil {
+			return nil, fmt.Errorf("%s: %w", e.Name(), err)
+		}
+		raw, err := os.ReadFile(filepath.Join(dir, e.Name(), logFile))
+		if err != nil {
+			return nil, err
+		}
+		c.Name = e.Name()
+		c.Log = string(raw)
+		cases = append(cases, c)
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("no cases in %s", dir)
+	}
+	return cases, nil
+}
+
+type Options struct {
+	Repo       string
+	Extractor  extract.Extractor
+	Classifier classify.Classifier
+}
+
+type ClassMetrics struct {
+	TruePositives  int     `json:"true_positives"`
+	FalsePositives int     `json:"false_positives"`
+	FalseNegatives int     `json:"false_negatives"`
+	Precision      float64 `json:"precision"`
+	Recall         float64 `json:"recall"`
+}
+
+type Report struct {
+	Cases               int                             `json:"cases"`
+	Expected            int                             `json:"expected"`
+	Extracted           int                             `json:"extracted"`
+	Matched             int                             `json:"matched"`
+	ExtractionPrecision float64                         `json:"extraction_precision"`
+	ExtractionRecall    float64                         `json:"extraction_recall"`
+	Classes             map[classify.Class]ClassMetrics `json:"classes"`
+	// OverSplit lists expected groups that ended up in several fingerprints;
+	// OverMerged lists fingerprints that cover several expected groups.
+	OverSplit  []string `json:"over_split"`
+	OverMerged []string `json:"over_merged"`
+	Mismatches []string `json:"mismatches"`
+}
+
+// Run replays every case through extract → fingerprint → classify against an
+// in-memory store, in corpus order, so history-based classifiers see the
+// earlier cases.
+func Run(ctx context.Context, cases []Case, opts Options) (Report, error) {
+	st := store.NewMemory()
+	report := Report{Cases: len(cases), Classes: map[classify.Class]ClassMetrics{}}
+	groupFPs := map[string]map[string]struct{}{}
+	fpGroups := map[string]map[string]struct{}{}
+	start := time.Now().Add(-time.Duration(len(cases)) * time.Hour)
+
+	for i, c := range cases {
+		occs := opts.Extractor.Extract(extract.Input{
+			Repo:       opts.Repo,
+			Workflow:   "eval",
+			RunID:      int64(i + 1),
+			HeadSHA:    c.HeadSHA,
+			Branch:     c.Branch,
+			Event:      c.Event,
+			JobID:      int64(i + 1),
+			JobName:    c.JobName,
+			RunnerOS:   c.RunnerOS,
+			OccurredAt: start.Add(time.Duration(i) * time.Hour),
+			RawLogText: c.Log,
+		})
+		report.Expected += len(c.Expected)
+		report.Extracted += len(occs)
+		matched := make([]bool, len(c.Expected))
+		for _, occ := range occs {
+			occ.Excerpt = sanitize.Scrub(occ.Excerpt)
+			occ = fingerprint.ForOccurrence(opts.Repo, occ)
+			if err := st.UpsertOccurrence(ctx, occ); err != nil {
+				return Report{}, err
+			}
+			res, err := opts.Classifier.Classify(ctx, st, occ)
+			if err != nil {
+				return Report{}, err
+			}
+			idx := matchExpected(c.Expected, matched, occ)
+			if idx < 0 {
+				report.Mismatches = append(report.Mismatches, fmt.Sprintf("%s: unexpected occurrence test=%q signature=%q", c.Name, occ.TestName, firstLine(occ.ErrorSignature)))
+				continue
+			}
+			matched[idx] = true
+			report.Matched++
+			exp := c.Expected[idx]
+			if res.Class == exp.Class {
+				m := report.Classes[exp.Class]
+				m.TruePositives++
+				report.Classes[exp.Class] = m
+			} else {
+				m := report.Classes[exp.Class]
+				m.FalseNegatives++
+				report.Classes[exp.Class] = m
+				m = report.Classes[res.Class]
+				m.FalsePositives++
+				report.Classes[res.Class] = m
+				report.Mismatches = append(report.Mismatches, fmt.Sprintf("%s: test=%q expected %s, got %s (%.2f)", c.Name, exp.TestName, exp.Class, res.Class, res.Confidence))
+			}
+			addToSet(groupFPs, exp.Group, occ.Fingerprint)
+			addToSet(fpGroups, occ.Fingerprint, exp.Group)
+		}
+		for i, ok := range matched {
+			if !ok {
+				report.Mismatches = append(report.Mismatches, fmt.Sprintf("%s: missed occurrence test=%q", c.Name, c.Expected[i].TestName))
+			}
+		}
+	}
+
+	report.ExtractionPrecision = ratio(report.Matched, report.Extracted)
+	report.ExtractionRecall = ratio(report.Matched, report.Expected)
+	for class, m := range report.Classes {
+		m.Precision = ratio(m.TruePositives, m.TruePositives+m.FalsePositives)
+		m.Recall = ratio(m.TruePositives, m.TruePositives+m.FalseNegatives)
+		report.Classes[class] = m
+	}
+	for group, fps := range groupFPs {
+		if len(fps) > 1 {
+			report.OverSplit = append(report.OverSplit, fmt.Sprintf("%s (%d fingerprints)", group, len(fps)))
+		}
+	}
+	for fp, groups := range fpGroups {
+		if len(groups) > 1 {
+			report.OverMerged = append(report.OverMerged, fmt.Sprintf("%s (%s)", shortFP(fp), strings.Join(sortedKeys(groups), ", ")))
+		}
+	}
+	sort.Strings(report.OverSplit)
+	sort.Strings(report.OverMerged)
+	return report, nil
+}
+
+func matchExpected(expected []ExpectedOccurrence, matched []bool, occ extract.Occurrence) int {
+	for i, exp := range expected {
+		if matched[i] || exp.TestName != occ.TestName {
+			continue
+		}
+		if exp.SignatureContains != "" && !strings.Contains(occ.ErrorSignature, exp.SignatureContains) {
+			continue
+		}
+		return i
+	}
+	return -1
+}
+
+func LoadReport(path string) (Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Report{}, err
+	}
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return Report{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return r, nil
+}
+
+func WriteReport(path string, r Report) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Diff describes every metric that changed from baseline to current and
+// reports whether any of them got worse.
+func Diff(baseline, current Report) ([]string, bool) {
+	var lines []string
+	regressed := false
+	metric := func(name string, before, after float64) {
+		if before == after {
+			return
+		}
+		lines = append(lines, fmt.Sprintf("%s: %.3f -> %.3f (%+.3f)", name, before, after, after-before))
+		if after < before {
+			regressed = true
+		}
+	}
+	count := func(name string, before, after int) {
+		if before == after {
+			return
+		}
+		lines = append(lines, fmt.Sprintf("%s: %d -> %d", name, before, after))
+		if after > before {
+			regressed = true
+		}
+	}
+	metric("extraction precision", baseline.ExtractionPrecision, current.ExtractionPrecision)
+	metric("extraction recall", baseline.ExtractionRecall, current.ExtractionRecall)
+	for _, class := range classUnion(baseline.Classes, current.Classes) {
+		b, c := baseline.Classes[class], current.Classes[class]
+		metric(string(class)+" precision", b.Precision, c.Precision)
+		metric(string(class)+" recall", b.Recall, c.Recall)
+	}
+	count("over-split groups", len(baseline.OverSplit), len(current.OverSplit))
+	count("over-merged fingerprints", len(baseline.OverMerged), len(current.OverMerged))
+	return lines, regressed
+}
+
+func (r Report) Format(w io.Writer) {
+	fmt.Fprintf(w, "cases=%d expected=%d extracted=%d matched=%d\n", r.Cases, r.Expected, r.Extracted, r.Matched)
+	fmt.Fprintf(w, "extraction precision=%.3f recall=%.3f\n", r.ExtractionPrecision, r.ExtractionRecall)
+	for _, class := range classUnion(r.Classes, nil) {
+		m := r.Classes[class]
+		fmt.Fprintf(w, "class %-18s precision=%.3f recall=%.3f (tp=%d fp=%d fn=%d)\n", class, m.Precision, m.Recall, m.TruePositives, m.FalsePositives, m.FalseNegatives)
+	}
+	for _, s := range r.OverSplit {
+		fmt.Fprintf(w, "over-split: %s\n", s)
+	}
+	for _, s := range r.OverMerged {
+		fmt.Fprintf(w, "over-merged: %s\n", s)
+	}
+	for _, s := range r.Mismatches {
+		fmt.Fprintf(w, "  %s\n", s)
+	}
+}
+
+func classUnion(a, b map[classify.Class]ClassMetrics) []classify.Class {
+	set := map[classify.Class]struct{}{}
+	for c := range a {
+		set[c] = struct{}{}
+	}
+	for c := range b {
+		set[c] = struct{}{}
+	}
+	out := make([]classify.Class, 0, len(set))
+	for c := range set {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+func addToSet(m map[string]map[string]struct{}, key, value string) {
+	if m[key] == nil {
+		m[key] = map[string]struct{}{}
+	}
+	m[key][value] = struct{}{}
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func ratio(a, b int) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
+
+func shortFP(fp string) string {
+	if len(fp) <= 12 {
+		return fp
+	}
+	return fp[:12]
+}
+
+func firstLine(s string) string {
+	return strings.TrimSpace(strings.SplitN(s, "\n", 2)[0])
+}
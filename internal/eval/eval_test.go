@@ -0,0 +1,43 @@
+package eval
+
+import (
+	"context"
+	"testing"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/classify"
+	"github.com/okJiang/flaky-test-cleaner/internal/extract"
+)
+
+func TestCorpusDoesNotRegressAgainstBaseline(t *testing.T) {
+	cases, err := LoadCorpus("testdata/corpus")
+	if err != nil {
+		t.Fatalf("load corpus: %v", err)
+	}
+	report, err := Run(context.Background(), cases, Options{
+		Repo:      "tikv/pd",
+		Extractor: extract.NewGoTestExtractor(),
+		Classifier: classify.NewComposite([]classify.Member{
+			{Name: "heuristic", Classifier: classify.NewHeuristic(), Weight: 1},
+			{Name: "history", Classifier: classify.NewHistory(classify.HistoryOptions{}), Weight: 1},
+		}, nil),
+	})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	baseline, err := LoadReport("testdata/corpus/baseline.json")
+	if err != nil {
+		t.Fatalf("load baseline: %v", err)
+	}
+	if diff, regressed := Diff(baseline, report); regressed {
+		t.Fatalf("regressed against baseline: %v", diff)
+	}
+}
+
+func TestDiffFlagsRegression(t *testing.T) {
+	base := Report{ExtractionRecall: 0.8, Classes: map[classify.Class]ClassMetrics{classify.ClassFlakyTest: {Recall: 0.5}}}
+	cur := Report{ExtractionRecall: 0.9, Classes: map[classify.Class]ClassMetrics{classify.ClassFlakyTest: {Recall: 0.4}}, OverSplit: []string{"g"}}
+	lines, regressed := Diff(base, cur)
+	if !regressed || len(lines) != 3 {
+		t.Fatalf("expected regression with 3 changes, got %v %v", regressed, lines)
+	}
+}
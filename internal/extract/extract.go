@@ -12,6 +12,9 @@ type Input struct {
 	RunID      int64
 	RunURL     string
 	HeadSHA    string
+	RunAttempt int
+	Branch     string
+	Event      string
 	JobID      int64
 	JobName    string
 	RunnerOS   string
@@ -26,15 +29,27 @@ type Occurrence struct {
 	RunID          int64
 	RunURL         string
 	HeadSHA        string
+	RunAttempt     int
+	Branch         string
+	Event          string
 	JobID          int64
 	JobName        string
 	RunnerOS       string
+	Step           string
 	OccurredAt     time.Time
 	Framework      string
 	TestName       string
 	ErrorSignature string
 	Excerpt        string
 	Fingerprint    string
+
+	// Package is the Go package the test belongs to, GoVersion the toolchain
+	// the job used, and Race and Failpoint whether the job ran with the race
+	// detector or failpoints enabled. Any of them may be unknown.
+	Package   string
+	GoVersion string
+	Race      bool
+	Failpoint bool
 }
 
 func (o Occurrence) PlatformBucket() string {
@@ -44,6 +59,15 @@ func (o Occurrence) PlatformBucket() string {
 	return o.RunnerOS
 }
 
+var (
+	reStepGroup     = regexp.MustCompile(`##\[group\](.+)$`)
+	rePackageResult = regexp.MustCompile(`(?:^|\s)(?:FAIL|ok)\s+([\w.~-]+(?:/[\w.~-]+)+)(?:\s|$)`)
+	rePackageJSON   = regexp.MustCompile(`"Package":"([^"]+)"`)
+	reGoVersion     = regexp.MustCompile(`\bgo version (go\d+\.\d+(?:\.\d+)?)`)
+	reRaceMarker    = regexp.MustCompile(`(?:\s-race\b|WARNING: DATA RACE)`)
+	reFailpoint     = regexp.MustCompile(`\b(?:make\b.*\bfailpoint-enable|failpoint-ctl\s+enable)\b`)
+)
+
 type Extractor interface {
 	Extract(in Input) []Occurrence
 }
@@ -72,9 +96,20 @@ func (e *GoTestExtractor) Extract(in Input) []Occurrence {
 		{regexp.MustCompile(`timeout`), "timeout"},
 	}
 
+	goVersion := ""
+	if m := reGoVersion.FindStringSubmatch(in.RawLogText); m != nil {
+		goVersion = m[1]
+	}
+	race := reRaceMarker.MatchString(in.RawLogText)
+	failpoint := reFailpoint.MatchString(in.RawLogText)
+
 	var out []Occurrence
 	seen := map[string]struct{}{}
+	step := ""
 	for i, line := range lines {
+		if m := reStepGroup.FindStringSubmatch(line); len(m) == 2 {
+			step = strings.TrimSpace(m[1])
+		}
 		for _, p := range patterns {
 			if !p.re.MatchString(line) {
 				continue
@@ -99,20 +134,43 @@ func (e *GoTestExtractor) Extract(in Input) []Occurrence {
 				RunID:          in.RunID,
 				RunURL:         in.RunURL,
 				HeadSHA:        in.HeadSHA,
+				RunAttempt:     in.RunAttempt,
+				Branch:         in.Branch,
+				Event:          in.Event,
 				JobID:          in.JobID,
 				JobName:        in.JobName,
 				RunnerOS:       in.RunnerOS,
+				Step:           step,
 				OccurredAt:     in.OccurredAt,
 				Framework:      "go test",
 				TestName:       name,
 				ErrorSignature: errorSig,
 				Excerpt:        excerpt,
+				Package:        packageAfter(lines, i),
+				GoVersion:      goVersion,
+				Race:           race,
+				Failpoint:      failpoint,
 			})
 		}
 	}
 	return out
 }
 
+// packageAfter finds the package of the test output at line i: go test -json
+// events carry it on every line, plain output reports it in the FAIL/ok line
+// that ends the package.
+func packageAfter(lines []string, i int) string {
+	if m := rePackageJSON.FindStringSubmatch(lines[i]); m != nil {
+		return m[1]
+	}
+	for _, line := range lines[i:] {
+		if m := rePackageResult.FindStringSubmatch(line); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
 func extractExcerpt(lines []string, center, before, after, max int) string {
 	start := center - before
 	if start < 0 {
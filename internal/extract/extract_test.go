@@ -37,3 +37,67 @@ func TestGoTestExtractorFindsFail(t *testing.T) {
 		t.Fatalf("expected excerpt")
 	}
 }
+
+func TestGoTestExtractorReproInfo(t *testing.T) {
+	log := strings.Join([]string{
+		"go version go1.22.1 linux/amd64",
+		"make failpoint-enable",
+		"go test -race -timeout 20m ./...",
+		"=== RUN   TestSuite/TestCase",
+		"--- FAIL: TestSuite/TestCase (1.00s)",
+		"    foo_test.go:12: expected true",
+		"FAIL",
+		"FAIL\tgithub.com/tikv/pd/pkg/foo\t3.2s",
+		`{"Action":"output","Package":"github.com/tikv/pd/pkg/bar","Test":"TestBar","Output":"--- FAIL: TestBar (0.01s)\n"}`,
+	}, "\n")
+
+	occs := NewGoTestExtractor().Extract(Input{Repo: "tikv/pd", RawLogText: log})
+	pkgs := map[string]string{}
+	for _, occ := range occs {
+		if occ.GoVersion != "go1.22.1" || !occ.Race || !occ.Failpoint {
+			t.Fatalf("unexpected markers %+v", occ)
+		}
+		pkgs[occ.TestName] = occ.Package
+	}
+	if pkgs["TestSuite/TestCase"] != "github.com/tikv/pd/pkg/foo" || pkgs["TestBar"] != "github.com/tikv/pd/pkg/bar" {
+		t.Fatalf("unexpected packages %v", pkgs)
+	}
+}
+
+func TestGoTestExtractorFailpointNeedsEnableStep(t *testing.T) {
+	log := strings.Join([]string{
+		"go test ./pkg/failpoint/...",
+		"--- FAIL: TestFailpointInject (0.10s)",
+		"    inject_test.go:20: failpoint not triggered",
+		"FAIL\tgithub.com/tikv/pd/pkg/failpoint\t0.2s",
+	}, "\n")
+	for _, occ := range NewGoTestExtractor().Extract(Input{Repo: "tikv/pd", RawLogText: log}) {
+		if occ.Failpoint {
+			t.Fatalf("failpoint mention taken for enable step: %+v", occ)
+		}
+	}
+	log = "bin/failpoint-ctl enable ./server\n" + log
+	for _, occ := range NewGoTestExtractor().Extract(Input{Repo: "tikv/pd", RawLogText: log}) {
+		if !occ.Failpoint {
+			t.Fatalf("failpoint-ctl enable not detected: %+v", occ)
+		}
+	}
+}
+
+func TestCountTestResults(t *testing.T) {
+	log := strings.Join([]string{
+		"2024-01-01T00:00:00.0000000Z --- PASS: TestFoo (0.01s)",
+		"--- FAIL: TestBar (0.02s)",
+		"--- PASS: TestBar (0.01s)",
+		`2024-01-01T00:00:01.0000000Z {"Action":"pass","Package":"github.com/tikv/pd/pkg/foo","Test":"TestFoo","Elapsed":0.01}`,
+		`{"Action":"output","Test":"TestFoo","Output":"--- PASS: TestFoo (0.01s)\n"}`,
+	}, "\n")
+
+	counts := CountTestResults(log)
+	if got := counts["TestFoo"]; got.Passed != 1 || got.Failed != 0 {
+		t.Fatalf("unexpected TestFoo counts %+v", got)
+	}
+	if got := counts["TestBar"]; got.Passed != 1 || got.Failed != 1 {
+		t.Fatalf("unexpected TestBar counts %+v", got)
+	}
+}
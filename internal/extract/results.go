@@ -0,0 +1,59 @@
+package extract
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// TestCounts is how often a single test passed or failed within one log.
+type TestCounts struct {
+	Passed int
+	Failed int
+}
+
+var reTestResult = regexp.MustCompile(`--- (PASS|FAIL): ([^\s]+)`)
+
+// CountTestResults tallies per-test outcomes from plain `go test -v` output and
+// from `go test -json` events found in a job log. A -json run usually echoes
+// its result lines as well, so a test with json events is counted from those
+// alone.
+func CountTestResults(raw string) map[string]TestCounts {
+	events, plain := map[string]TestCounts{}, map[string]TestCounts{}
+	for _, line := range strings.Split(raw, "\n") {
+		if idx := strings.Index(line, `{"`); idx >= 0 && strings.Contains(line, `"Action"`) {
+			var ev struct {
+				Action string
+				Test   string
+			}
+			if err := json.Unmarshal([]byte(line[idx:]), &ev); err == nil {
+				if ev.Test != "" {
+					tally(events, ev.Test, ev.Action)
+				}
+				continue
+			}
+		}
+		if m := reTestResult.FindStringSubmatch(line); len(m) == 3 {
+			tally(plain, m[2], strings.ToLower(m[1]))
+		}
+	}
+	for test, c := range plain {
+		if _, ok := events[test]; !ok {
+			events[test] = c
+		}
+	}
+	return events
+}
+
+func tally(out map[string]TestCounts, test, action string) {
+	c := out[test]
+	switch action {
+	case "pass":
+		c.Passed++
+	case "fail":
+		c.Failed++
+	default:
+		return
+	}
+	out[test] = c
+}
@@ -0,0 +1,137 @@
+package feedback
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/github"
+	"github.com/okJiang/flaky-test-cleaner/internal/store"
+)
+
+// Labels a maintainer can put on a managed issue to record a verdict.
+// Removing the flaky-test label the bot applied counts as "not flaky".
+var labelVerdicts = map[string]string{
+	"flaky-test-cleaner/flaky-test": store.VerdictFlaky,
+	"flaky-test-cleaner/not-flaky":  store.VerdictNotFlaky,
+	"flaky-test-cleaner/regression": store.VerdictRegression,
+	"flaky-test-cleaner/infra":      store.VerdictInfra,
+}
+
+var (
+	reSlashCommand = regexp.MustCompile(`(?m)^\s*/ftc\s+(flaky|not-flaky|regression|infra)\b`)
+	reRealBug      = regexp.MustCompile(`(?i)\b(not\s+)?(a\s+)?real\s+(bug|regression)\b`)
+	reNotFlaky     = regexp.MustCompile(`(?i)\bnot\s+(a\s+)?flak(y|e)\b`)
+)
+
+type Options struct {
+	Owner string
+	Repo  string
+	// BotLogin is the account the tool writes issues as; its own events and
+	// comments are never treated as feedback.
+	BotLogin string
+}
+
+type Collector struct {
+	gh   *github.Client
+	opts Options
+}
+
+func NewCollector(gh *github.Client, opts Options) *Collector {
+	return &Collector{gh: gh, opts: opts}
+}
+
+// Collect returns every maintainer verdict found in the close reason, label
+// changes and comments of iss, the issue linked to rec.
+func (c *Collector) Collect(ctx context.Context, rec store.FingerprintRecord, iss github.Issue) ([]store.Feedback, error) {
+	events, err := c.gh.ListIssueEvents(ctx, c.opts.Owner, c.opts.Repo, rec.IssueNumber)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := c.gh.ListIssueComments(ctx, c.opts.Owner, c.opts.Repo, rec.IssueNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []store.Feedback
+	add := func(sourceID, source, verdict string, actor github.User, at time.Time) {
+		out = append(out, store.Feedback{
+			Fingerprint: rec.Fingerprint,
+			Repo:        rec.Repo,
+			TestName:    rec.TestName,
+			IssueNumber: rec.IssueNumber,
+			SourceID:    sourceID,
+			Source:      source,
+			Verdict:     verdict,
+			Actor:       actor.Login,
+			CreatedAt:   at,
+		})
+	}
+	for _, ev := range events {
+		if c.isBot(ev.Actor) {
+			continue
+		}
+		id := fmt.Sprintf("event:%d", ev.ID)
+		switch ev.Event {
+		case "closed":
+			reason := ev.StateReason
+			if reason == "" {
+				reason = iss.StateReason
+			}
+			if v := closeVerdict(reason); v != "" {
+				add(id, "state", v, ev.Actor, ev.CreatedAt)
+			}
+		case "labeled":
+			if v, ok := labelVerdicts[ev.Label.Name]; ok {
+				add(id, "label", v, ev.Actor, ev.CreatedAt)
+			}
+		case "unlabeled":
+			if labelVerdicts[ev.Label.Name] == store.VerdictFlaky {
+				add(id, "label", store.VerdictNotFlaky, ev.Actor, ev.CreatedAt)
+			}
+		}
+	}
+	for _, cm := range comments {
+		if c.isBot(cm.User) {
+			continue
+		}
+		if v := CommentVerdict(cm.Body); v != "" {
+			add(fmt.Sprintf("comment:%d", cm.ID), "comment", v, cm.User, cm.CreatedAt)
+		}
+	}
+	return out, nil
+}
+
+func (c *Collector) isBot(u github.User) bool {
+	return u.Type == "Bot" || (c.opts.BotLogin != "" && strings.EqualFold(u.Login, c.opts.BotLogin))
+}
+
+func closeVerdict(reason string) string {
+	switch reason {
+	case "not_planned":
+		return store.VerdictNotFlaky
+	case "completed":
+		return store.VerdictFixed
+	}
+	return ""
+}
+
+// CommentVerdict extracts a verdict from a comment. A `/ftc <verdict>` line
+// wins; otherwise a few plain phrases such as "this is a real bug" count.
+func CommentVerdict(body string) string {
+	if m := reSlashCommand.FindStringSubmatch(body); m != nil {
+		return m[1]
+	}
+	if m := reRealBug.FindStringSubmatch(body); m != nil {
+		if m[1] != "" {
+			return store.VerdictFlaky
+		}
+		return store.VerdictRegression
+	}
+	if reNotFlaky.MatchString(body) {
+		return store.VerdictNotFlaky
+	}
+	return ""
+}
@@ -0,0 +1,24 @@
+package feedback
+
+import (
+	"testing"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/store"
+)
+
+func TestCommentVerdict(t *testing.T) {
+	cases := map[string]string{
+		"/ftc not-flaky":                          store.VerdictNotFlaky,
+		"Looked into it.\n/ftc infra\nthanks":     store.VerdictInfra,
+		"This is a real bug, see #123":            store.VerdictRegression,
+		"this is not a real bug, just the runner": store.VerdictFlaky,
+		"Definitely not flaky.":                   store.VerdictNotFlaky,
+		"any update?":                             "",
+		"please run /ftc flaky":                   "",
+	}
+	for body, want := range cases {
+		if got := CommentVerdict(body); got != want {
+			t.Errorf("CommentVerdict(%q) = %q, want %q", body, got, want)
+		}
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"regexp"
+	"sort"
 	"strings"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/extract"
 )
 
 type V1Input struct {
@@ -20,6 +23,27 @@ func V1(in V1Input) string {
 	return hex.EncodeToString(h[:])
 }
 
+// ForOccurrence normalizes the occurrence's error signature and sets its v1
+// fingerprint. Aliases are not resolved here.
+func ForOccurrence(repo string, occ extract.Occurrence) extract.Occurrence {
+	occ.ErrorSignature = NormalizeErrorSignature(occ.ErrorSignature)
+	occ.Fingerprint = V1(V1Input{
+		Repo:         repo,
+		Framework:    occ.Framework,
+		TestName:     occ.TestName,
+		ErrorSigNorm: occ.ErrorSignature,
+		Platform:     occ.PlatformBucket(),
+	})
+	return occ
+}
+
+func Split(base string, signatures []string) string {
+	sigs := append([]string(nil), signatures...)
+	sort.Strings(sigs)
+	h := sha256.Sum256([]byte(base + "|split|" + strings.Join(sigs, "|")))
+	return hex.EncodeToString(h[:])
+}
+
 func NormalizeErrorSignature(s string) string {
 	if s == "" {
 		return s
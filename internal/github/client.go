@@ -38,16 +38,20 @@ type Workflow struct {
 }
 
 type WorkflowRun struct {
-	ID        int64     `json:"id"`
-	HTMLURL   string    `json:"html_url"`
-	HeadSHA   string    `json:"head_sha"`
-	CreatedAt time.Time `json:"created_at"`
+	ID         int64     `json:"id"`
+	HTMLURL    string    `json:"html_url"`
+	HeadSHA    string    `json:"head_sha"`
+	HeadBranch string    `json:"head_branch"`
+	Event      string    `json:"event"`
+	RunAttempt int       `json:"run_attempt"`
+	CreatedAt  time.Time `json:"created_at"`
 }
 
 type Job struct {
 	ID         int64    `json:"id"`
 	Name       string   `json:"name"`
 	Conclusion string   `json:"conclusion"`
+	RunAttempt int      `json:"run_attempt"`
 	RunnerName string   `json:"runner_name"`
 	RunnerOS   string   `json:"-"`
 	Labels     []string `json:"labels"`
@@ -96,6 +100,25 @@ func (c *Client) ListWorkflowRuns(ctx context.Context, owner, repo string, workf
 }
 
 func (c *Client) ListRunJobs(ctx context.Context, owner, repo string, runID int64, opts ListRunJobsOptions) ([]Job, error) {
+	path := fmt.Sprintf("/repos/%s/%s/actions/runs/%d/jobs", owner, repo, runID)
+	return c.listJobs(ctx, path, opts)
+}
+
+func (c *Client) ListRunAttemptJobs(ctx context.Context, owner, repo string, runID int64, attempt int, opts ListRunJobsOptions) ([]Job, error) {
+	path := fmt.Sprintf("/repos/%s/%s/actions/runs/%d/attempts/%d/jobs", owner, repo, runID, attempt)
+	jobs, err := c.listJobs(ctx, path, opts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range jobs {
+		if jobs[i].RunAttempt == 0 {
+			jobs[i].RunAttempt = attempt
+		}
+	}
+	return jobs, nil
+}
+
+func (c *Client) listJobs(ctx context.Context, path string, opts ListRunJobsOptions) ([]Job, error) {
 	query := url.Values{}
 	if opts.PerPage > 0 {
 		query.Set("per_page", strconv.Itoa(opts.PerPage))
@@ -103,7 +126,6 @@ func (c *Client) ListRunJobs(ctx context.Context, owner, repo string, runID int6
 	var res struct {
 		Jobs []Job `json:"jobs"`
 	}
-	path := fmt.Sprintf("/repos/%s/%s/actions/runs/%d/jobs", owner, repo, runID)
 	if err := c.doJSON(ctx, http.MethodGet, path, query, nil, &res); err != nil {
 		return nil, err
 	}
@@ -123,9 +145,33 @@ func (c *Client) DownloadJobLogs(ctx context.Context, owner, repo string, jobID
 }
 
 type Issue struct {
-	Number int    `json:"number"`
-	Title  string `json:"title"`
-	Body   string `json:"body"`
+	NodeID      string     `json:"node_id"`
+	Number      int        `json:"number"`
+	Title       string     `json:"title"`
+	Body        string     `json:"body"`
+	State       string     `json:"state"`
+	StateReason string     `json:"state_reason"`
+	Labels      []Label    `json:"labels"`
+	Assignees   []User     `json:"assignees"`
+	ClosedAt    *time.Time `json:"closed_at"`
+}
+
+type Label struct {
+	Name string `json:"name"`
+}
+
+type User struct {
+	Login string `json:"login"`
+	Type  string `json:"type"`
+}
+
+type IssueEvent struct {
+	ID          int64     `json:"id"`
+	Event       string    `json:"event"`
+	Actor       User      `json:"actor"`
+	Label       Label     `json:"label"`
+	StateReason string    `json:"state_reason"`
+	CreatedAt   time.Time `json:"created_at"`
 }
 
 type CreateIssueInput struct {
@@ -135,9 +181,62 @@ type CreateIssueInput struct {
 }
 
 type UpdateIssueInput struct {
-	Title  *string
-	Body   *string
-	Labels []string
+	Title       *string
+	Body        *string
+	State       *string
+	StateReason *string
+}
+
+type IssueComment struct {
+	ID        int64     `json:"id"`
+	Body      string    `json:"body"`
+	HTMLURL   string    `json:"html_url"`
+	User      User      `json:"user"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ListIssuesOptions struct {
+	Labels  string
+	State   string
+	PerPage int
+	Page    int
+}
+
+func (c *Client) ListIssues(ctx context.Context, owner, repo string, opts ListIssuesOptions) ([]Issue, error) {
+	query := url.Values{}
+	if opts.Labels != "" {
+		query.Set("labels", opts.Labels)
+	}
+	if opts.State != "" {
+		query.Set("state", opts.State)
+	}
+	if opts.PerPage > 0 {
+		query.Set("per_page", strconv.Itoa(opts.PerPage))
+	}
+	if opts.Page > 0 {
+		query.Set("page", strconv.Itoa(opts.Page))
+	}
+	var res []Issue
+	path := fmt.Sprintf("/repos/%s/%s/issues", owner, repo)
+	if err := c.doJSON(ctx, http.MethodGet, path, query, nil, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// SearchIssues runs an issue search query; results may lag recent edits.
+func (c *Client) SearchIssues(ctx context.Context, query string, perPage int) ([]Issue, error) {
+	values := url.Values{"q": {query}}
+	if perPage > 0 {
+		values.Set("per_page", strconv.Itoa(perPage))
+	}
+	var res struct {
+		Items []Issue `json:"items"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, "/search/issues", values, nil, &res); err != nil {
+		return nil, err
+	}
+	return res.Items, nil
 }
 
 func (c *Client) GetIssue(ctx context.Context, owner, repo string, number int) (Issue, error) {
@@ -171,8 +270,11 @@ func (c *Client) UpdateIssue(ctx context.Context, owner, repo string, number int
 	if in.Body != nil {
 		payload["body"] = *in.Body
 	}
-	if in.Labels != nil {
-		payload["labels"] = in.Labels
+	if in.State != nil {
+		payload["state"] = *in.State
+	}
+	if in.StateReason != nil {
+		payload["state_reason"] = *in.StateReason
 	}
 	var res Issue
 	path := fmt.Sprintf("/repos/%s/%s/issues/%d", owner, repo, number)
@@ -182,6 +284,59 @@ func (c *Client) UpdateIssue(ctx context.Context, owner, repo string, number int
 	return res, nil
 }
 
+func (c *Client) CreateComment(ctx context.Context, owner, repo string, number int, body string) (IssueComment, error) {
+	var res IssueComment
+	path := fmt.Sprintf("/repos/%s/%s/issues/%d/comments", owner, repo, number)
+	if err := c.doJSON(ctx, http.MethodPost, path, nil, map[string]any{"body": body}, &res); err != nil {
+		return IssueComment{}, err
+	}
+	return res, nil
+}
+
+func (c *Client) UpdateComment(ctx context.Context, owner, repo string, commentID int64, body string) (IssueComment, error) {
+	var res IssueComment
+	path := fmt.Sprintf("/repos/%s/%s/issues/comments/%d", owner, repo, commentID)
+	if err := c.doJSON(ctx, http.MethodPatch, path, nil, map[string]any{"body": body}, &res); err != nil {
+		return IssueComment{}, err
+	}
+	return res, nil
+}
+
+// ListIssueComments returns every comment on an issue, oldest first.
+func (c *Client) ListIssueComments(ctx context.Context, owner, repo string, number int) ([]IssueComment, error) {
+	path := fmt.Sprintf("/repos/%s/%s/issues/%d/comments", owner, repo, number)
+	return listAll[IssueComment](ctx, c, path, url.Values{"per_page": {"100"}})
+}
+
+// ListIssueEvents returns every event of an issue, oldest first.
+func (c *Client) ListIssueEvents(ctx context.Context, owner, repo string, number int) ([]IssueEvent, error) {
+	path := fmt.Sprintf("/repos/%s/%s/issues/%d/events", owner, repo, number)
+	return listAll[IssueEvent](ctx, c, path, url.Values{"per_page": {"100"}})
+}
+
+func (c *Client) GetAuthenticatedUser(ctx context.Context) (User, error) {
+	var res User
+	if err := c.doJSON(ctx, http.MethodGet, "/user", nil, nil, &res); err != nil {
+		return User{}, err
+	}
+	return res, nil
+}
+
+func (c *Client) AddLabels(ctx context.Context, owner, repo string, number int, labels []string) error {
+	path := fmt.Sprintf("/repos/%s/%s/issues/%d/labels", owner, repo, number)
+	return c.doJSON(ctx, http.MethodPost, path, nil, map[string]any{"labels": labels}, nil)
+}
+
+// RemoveLabel removes one label from an issue; a label that is already gone is
+// not an error.
+func (c *Client) RemoveLabel(ctx context.Context, owner, repo string, number int, label string) error {
+	path := fmt.Sprintf("/repos/%s/%s/issues/%d/labels/%s", owner, repo, number, url.PathEscape(label))
+	if err := c.doJSON(ctx, http.MethodDelete, path, nil, nil, nil); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
 func (c *Client) EnsureLabels(ctx context.Context, owner, repo string, labels []string) error {
 	for _, label := range labels {
 		if strings.TrimSpace(label) == "" {
@@ -198,6 +353,101 @@ func (c *Client) EnsureLabels(ctx context.Context, owner, repo string, labels []
 	return nil
 }
 
+// AddAssignees assigns users to an issue; GitHub silently ignores users who
+// cannot be assigned.
+func (c *Client) AddAssignees(ctx context.Context, owner, repo string, number int, logins []string) error {
+	path := fmt.Sprintf("/repos/%s/%s/issues/%d/assignees", owner, repo, number)
+	return c.doJSON(ctx, http.MethodPost, path, nil, map[string]any{"assignees": logins}, nil)
+}
+
+func (c *Client) IsCollaborator(ctx context.Context, owner, repo, login string) (bool, error) {
+	path := fmt.Sprintf("/repos/%s/%s/collaborators/%s", owner, repo, url.PathEscape(login))
+	err := c.doJSON(ctx, http.MethodGet, path, nil, nil, nil)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// GetFileContents returns the raw content of a file at ref.
+func (c *Client) GetFileContents(ctx context.Context, owner, repo, filePath, ref string) ([]byte, error) {
+	segments := strings.Split(strings.Trim(filePath, "/"), "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+	path := fmt.Sprintf("/repos/%s/%s/contents/%s", owner, repo, strings.Join(segments, "/"))
+	var query url.Values
+	if ref != "" {
+		query = url.Values{"ref": {ref}}
+	}
+	respBody, status, err := c.do(ctx, http.MethodGet, path, query, nil, "application/vnd.github.raw")
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if status < 200 || status >= 300 {
+		return nil, &apiError{StatusCode: status, Message: string(respBody)}
+	}
+	return respBody, nil
+}
+
+type Commit struct {
+	SHA    string `json:"sha"`
+	Author *User  `json:"author"`
+}
+
+type ListCommitsOptions struct {
+	SHA     string
+	Path    string
+	PerPage int
+}
+
+func (c *Client) ListCommits(ctx context.Context, owner, repo string, opts ListCommitsOptions) ([]Commit, error) {
+	values := url.Values{}
+	if opts.SHA != "" {
+		values.Set("sha", opts.SHA)
+	}
+	if opts.Path != "" {
+		values.Set("path", opts.Path)
+	}
+	if opts.PerPage > 0 {
+		values.Set("per_page", strconv.Itoa(opts.PerPage))
+	}
+	var res []Commit
+	path := fmt.Sprintf("/repos/%s/%s/commits", owner, repo)
+	if err := c.doJSON(ctx, http.MethodGet, path, values, nil, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// PinIssue pins an issue to the repository's issue list; a repository pins
+// at most three issues.
+func (c *Client) PinIssue(ctx context.Context, nodeID string) error {
+	return c.graphQL(ctx, `mutation($id: ID!) { pinIssue(input: {issueId: $id}) { issue { number } } }`, map[string]any{"id": nodeID})
+}
+
+func (c *Client) UnpinIssue(ctx context.Context, nodeID string) error {
+	return c.graphQL(ctx, `mutation($id: ID!) { unpinIssue(input: {issueId: $id}) { issue { number } } }`, map[string]any{"id": nodeID})
+}
+
+func (c *Client) graphQL(ctx context.Context, query string, variables map[string]any) error {
+	var res struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := c.doJSON(ctx, http.MethodPost, "/graphql", nil, map[string]any{"query": query, "variables": variables}, &res); err != nil {
+		return err
+	}
+	if len(res.Errors) > 0 {
+		return &apiError{StatusCode: http.StatusOK, Message: res.Errors[0].Message}
+	}
+	return nil
+}
+
 var ErrNotFound = errors.New("not found")
 
 type apiError struct {
@@ -249,14 +499,63 @@ func (c *Client) doBytes(ctx context.Context, method, path string, query url.Val
 	return respBody, nil
 }
 
+// maxListPages bounds listAll, as a guard against endless Link chains.
+const maxListPages = 50
+
+// listAll fetches every page of a list endpoint by following the "next"
+// relation of the Link header.
+func listAll[T any](ctx context.Context, c *Client, path string, query url.Values) ([]T, error) {
+	urlStr := c.baseURL + path
+	if len(query) > 0 {
+		urlStr += "?" + query.Encode()
+	}
+	var all []T
+	for page := 0; urlStr != "" && page < maxListPages; page++ {
+		respBody, status, header, err := c.doURL(ctx, http.MethodGet, urlStr, nil, "application/vnd.github+json")
+		if err != nil {
+			return nil, err
+		}
+		if status == http.StatusNotFound {
+			return nil, ErrNotFound
+		}
+		if status < 200 || status >= 300 {
+			return nil, &apiError{StatusCode: status, Message: string(respBody)}
+		}
+		var items []T
+		if err := json.Unmarshal(respBody, &items); err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+		urlStr = nextLink(header.Get("Link"))
+	}
+	return all, nil
+}
+
+// nextLink returns the "next" URL of a Link header, or "" on the last page.
+func nextLink(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		target, params, ok := strings.Cut(strings.TrimSpace(part), ";")
+		if !ok || !strings.Contains(params, `rel="next"`) {
+			continue
+		}
+		return strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(target), "<"), ">")
+	}
+	return ""
+}
+
 func (c *Client) do(ctx context.Context, method, path string, query url.Values, body io.Reader, accept string) ([]byte, int, error) {
 	urlStr := c.baseURL + path
 	if query != nil && len(query) > 0 {
 		urlStr = urlStr + "?" + query.Encode()
 	}
+	b, status, _, err := c.doURL(ctx, method, urlStr, body, accept)
+	return b, status, err
+}
+
+func (c *Client) doURL(ctx context.Context, method, urlStr string, body io.Reader, accept string) ([]byte, int, http.Header, error) {
 	req, err := http.NewRequestWithContext(ctx, method, urlStr, body)
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, nil, err
 	}
 	if c.token != "" {
 		req.Header.Set("Authorization", "Bearer "+c.token)
@@ -269,7 +568,7 @@ func (c *Client) do(ctx context.Context, method, path string, query url.Values,
 	for attempt := 0; attempt < 2; attempt++ {
 		resp, err := c.http.Do(req)
 		if err != nil {
-			return nil, 0, err
+			return nil, 0, nil, err
 		}
 		b, _ := io.ReadAll(resp.Body)
 		_ = resp.Body.Close()
@@ -281,9 +580,9 @@ func (c *Client) do(ctx context.Context, method, path string, query url.Values,
 				continue
 			}
 		}
-		return b, resp.StatusCode, nil
+		return b, resp.StatusCode, resp.Header, nil
 	}
-	return nil, 0, errors.New("github request failed after retries")
+	return nil, 0, nil, errors.New("github request failed after retries")
 }
 
 func retryAfter(resp *http.Response) time.Duration {
@@ -0,0 +1,135 @@
+package issue
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Managed sections of an issue body are delimited by
+// `<!-- FTC:<NAME>_START -->` and `<!-- FTC:<NAME>_END -->`. Everything outside
+// them belongs to humans and is never rewritten.
+var reBlockMarker = regexp.MustCompile(`<!-- FTC:([A-Z0-9_]+)_(START|END) -->`)
+
+type block struct {
+	name  string
+	start int // offset of the START marker
+	end   int // offset just past the END marker
+}
+
+type marker struct {
+	name       string
+	start, end int
+	open       bool
+}
+
+// parseBlocks returns the well-formed blocks of body in order, plus the
+// offsets of markers that do not pair up (a START without END, an END without
+// START, or a START reopened before its END).
+func parseBlocks(body string) ([]block, [][2]int) {
+	var markers []marker
+	for _, m := range reBlockMarker.FindAllStringSubmatchIndex(body, -1) {
+		markers = append(markers, marker{
+			name:  body[m[2]:m[3]],
+			start: m[0],
+			end:   m[1],
+			open:  body[m[4]:m[5]] == "START",
+		})
+	}
+	var blocks []block
+	var orphans [][2]int
+	var open *marker
+	for i := range markers {
+		mk := markers[i]
+		switch {
+		case mk.open:
+			if open != nil {
+				orphans = append(orphans, [2]int{open.start, open.end})
+			}
+			open = &markers[i]
+		case open != nil && open.name == mk.name:
+			blocks = append(blocks, block{name: mk.name, start: open.start, end: mk.end})
+			open = nil
+		default:
+			orphans = append(orphans, [2]int{mk.start, mk.end})
+		}
+	}
+	if open != nil {
+		orphans = append(orphans, [2]int{open.start, open.end})
+	}
+	return blocks, orphans
+}
+
+// PatchBody rewrites the managed blocks of existing with those of rendered.
+// Blocks missing from existing are appended, duplicated or stale managed
+// blocks and unpaired markers are dropped, and all other text is kept as is.
+// Patching twice with the same rendered body is a no-op.
+func PatchBody(existing, rendered string) string {
+	if strings.TrimSpace(existing) == "" {
+		return rendered
+	}
+	fresh := map[string]string{}
+	var order []string
+	newBlocks, _ := parseBlocks(rendered)
+	for _, b := range newBlocks {
+		if _, ok := fresh[b.name]; ok {
+			continue
+		}
+		fresh[b.name] = rendered[b.start:b.end]
+		order = append(order, b.name)
+	}
+
+	type edit struct {
+		start, end int
+		text       string
+	}
+	var edits []edit
+	blocks, orphans := parseBlocks(existing)
+	seen := map[string]bool{}
+	for _, b := range blocks {
+		text, ok := fresh[b.name]
+		if !ok || seen[b.name] {
+			edits = append(edits, edit{start: b.start, end: b.end})
+			continue
+		}
+		seen[b.name] = true
+		edits = append(edits, edit{start: b.start, end: b.end, text: text})
+	}
+	for _, o := range orphans {
+		if insideAny(o, blocks) {
+			continue
+		}
+		edits = append(edits, edit{start: o[0], end: o[1]})
+	}
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+
+	var sb strings.Builder
+	pos := 0
+	for _, e := range edits {
+		sb.WriteString(existing[pos:e.start])
+		sb.WriteString(e.text)
+		pos = e.end
+	}
+	sb.WriteString(existing[pos:])
+	out := sb.String()
+
+	var missing []string
+	for _, name := range order {
+		if !seen[name] {
+			missing = append(missing, fresh[name])
+		}
+	}
+	if len(missing) > 0 {
+		out = strings.TrimRight(out, "\n") + "\n\n" + joinBlocks(missing...)
+	}
+	return out
+}
+
+func insideAny(span [2]int, blocks []block) bool {
+	for _, b := range blocks {
+		if span[0] >= b.start && span[1] <= b.end {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package issue
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/github"
+)
+
+func TestPatchBodyKeepsHumanText(t *testing.T) {
+	old := "Maintainer notes: seen on arm64 too.\n\n" +
+		wrapBlock("SUMMARY", "old summary") + "\n\nMore notes in the middle.\n\n" +
+		wrapBlock("EVIDENCE", "old evidence") + "\n\n/cc @someone\n"
+	rendered := joinBlocks(wrapBlock("SUMMARY", "new summary"), wrapBlock("EVIDENCE", "new evidence"), wrapBlock("AUTOMATION", "automation"))
+
+	got := PatchBody(old, rendered)
+	for _, want := range []string{"Maintainer notes: seen on arm64 too.", "More notes in the middle.", "/cc @someone", "new summary", "new evidence", "automation"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("patched body lost %q:\n%s", want, got)
+		}
+	}
+	for _, gone := range []string{"old summary", "old evidence"} {
+		if strings.Contains(got, gone) {
+			t.Fatalf("patched body still has %q:\n%s", gone, got)
+		}
+	}
+	if strings.Index(got, "/cc @someone") > strings.Index(got, "FTC:AUTOMATION_START") {
+		t.Fatalf("expected missing block appended at the end:\n%s", got)
+	}
+	if again := PatchBody(got, rendered); again != got {
+		t.Fatalf("patch is not idempotent:\n%s\n---\n%s", got, again)
+	}
+}
+
+func TestPatchBodyEmptyExisting(t *testing.T) {
+	rendered := joinBlocks(wrapBlock("SUMMARY", "s"))
+	if got := PatchBody("", rendered); got != rendered {
+		t.Fatalf("expected rendered body, got %q", got)
+	}
+}
+
+func TestPatchBodyMalformedMarkers(t *testing.T) {
+	rendered := joinBlocks(wrapBlock("SUMMARY", "new summary"), wrapBlock("EVIDENCE", "new evidence"))
+	cases := map[string]string{
+		"start without end": "notes\n<!-- FTC:SUMMARY_START -->\nhalf deleted\n",
+		"end without start": "notes\nhalf deleted\n<!-- FTC:EVIDENCE_END -->\n",
+		"reopened start":    "notes\n<!-- FTC:SUMMARY_START -->\nx\n" + wrapBlock("SUMMARY", "old") + "\n",
+		"duplicate blocks":  wrapBlock("SUMMARY", "one") + "\nnotes\n" + wrapBlock("SUMMARY", "two") + "\n",
+		"stale block":       wrapBlock("OLD_SECTION", "gone") + "\nnotes\n",
+	}
+	for name, old := range cases {
+		got := PatchBody(old, rendered)
+		if !strings.Contains(got, "notes") {
+			t.Fatalf("%s: lost human text:\n%s", name, got)
+		}
+		blocks, orphans := parseBlocks(got)
+		if len(orphans) != 0 {
+			t.Fatalf("%s: left unpaired markers:\n%s", name, got)
+		}
+		if len(blocks) != 2 || blocks[0].name != "SUMMARY" || blocks[1].name != "EVIDENCE" {
+			t.Fatalf("%s: unexpected blocks %+v:\n%s", name, blocks, got)
+		}
+		if again := PatchBody(got, rendered); again != got {
+			t.Fatalf("%s: patch is not idempotent:\n%s\n---\n%s", name, got, again)
+		}
+	}
+}
+
+func TestFingerprintFromBody(t *testing.T) {
+	body := "Quoting another issue: Fingerprint: `ffff`\n\n" +
+		wrapBlock("SUMMARY", "summary") + "\n\n" +
+		wrapBlock("AUTOMATION", "## Automation\n\n- Fingerprint: `abc123`\n")
+	if got := FingerprintFromBody(body); got != "abc123" {
+		t.Fatalf("expected abc123, got %q", got)
+	}
+	if got := FingerprintFromBody("- Fingerprint: `abc123`"); got != "" {
+		t.Fatalf("expected no fingerprint outside the AUTOMATION block, got %q", got)
+	}
+}
+
+func TestFindAdoptedIssueByComment(t *testing.T) {
+	managed := "Tracking this here.\n\n" + wrapBlock("AUTOMATION", "## Automation\n\n- Fingerprint: `abc123`\n")
+	items := []github.Issue{
+		{Number: 9, Title: "[flaky] TestFoo", Body: wrapBlock("AUTOMATION", "- Fingerprint: `def456`\n"), Labels: []github.Label{{Name: LabelAIManaged}}},
+		{Number: 7, Title: "TestFoo is flaky", Body: "seen twice this week", Labels: []github.Label{{Name: LabelAIManaged}}},
+		{Number: 3, Title: "TestFoo is flaky", Body: "not adopted"},
+	}
+	comments := func(number int) ([]github.IssueComment, error) {
+		if number != 7 {
+			t.Fatalf("unexpected comment lookup on #%d", number)
+		}
+		return []github.IssueComment{{Body: "+1"}, {Body: managed}}, nil
+	}
+	iss, ok, err := oldestWithFingerprint(items, "abc123", comments)
+	if err != nil || !ok || iss.Number != 7 {
+		t.Fatalf("expected adopted #7 relinked, got #%d ok=%v err=%v", iss.Number, ok, err)
+	}
+}
@@ -0,0 +1,85 @@
+package issue
+
+import (
+	"regexp"
+	"strings"
+)
+
+// codeownersPaths are the locations GitHub reads a CODEOWNERS file from, in
+// order of precedence.
+var codeownersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+type codeownersRule struct {
+	re     *regexp.Regexp
+	owners []string
+}
+
+// parseCodeowners reads the rules of a CODEOWNERS file. Lines with patterns
+// that cannot be translated are skipped.
+func parseCodeowners(data string) []codeownersRule {
+	var rules []codeownersRule
+	for _, line := range strings.Split(data, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		re, err := regexp.Compile(codeownersPattern(fields[0]))
+		if err != nil {
+			continue
+		}
+		rules = append(rules, codeownersRule{re: re, owners: fields[1:]})
+	}
+	return rules
+}
+
+// codeownersPattern translates a gitignore-style CODEOWNERS pattern into a
+// regular expression over slash-separated paths relative to the root.
+func codeownersPattern(p string) string {
+	dirOnly := strings.HasSuffix(p, "/")
+	p = strings.TrimSuffix(p, "/")
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+
+	var b strings.Builder
+	if anchored {
+		b.WriteString("^")
+	} else {
+		b.WriteString("^(?:.*/)?")
+	}
+	for i := 0; i < len(p); i++ {
+		switch {
+		case strings.HasPrefix(p[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(p[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case p[i] == '*':
+			b.WriteString("[^/]*")
+		case p[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(p[i : i+1]))
+		}
+	}
+	if dirOnly {
+		b.WriteString("/.*$")
+	} else {
+		b.WriteString("(?:/.*)?$")
+	}
+	return b.String()
+}
+
+// matchCodeowners returns the owners of path; as in GitHub, the last matching
+// rule wins, and a matching rule without owners means nobody owns the path.
+func matchCodeowners(rules []codeownersRule, path string) []string {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].re.MatchString(path) {
+			return rules[i].owners
+		}
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package issue
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatchCodeowners(t *testing.T) {
+	rules := parseCodeowners(strings.Join([]string{
+		"# default owners",
+		"*                 @tikv/pd-maintainers",
+		"*.md              @docs-team",
+		"/server/          @alice @bob # server code",
+		"pkg/schedule/**   @carol",
+		"tests/**/mcs      @dave",
+		"docs/             ops@example.com",
+		"/server/api/v2",
+	}, "\n"))
+	cases := map[string]string{
+		"pkg/foo/foo_test.go":                    "@tikv/pd-maintainers",
+		"README.md":                              "@docs-team",
+		"server/server_test.go":                  "@alice @bob",
+		"pkg/server/server_test.go":              "@tikv/pd-maintainers",
+		"pkg/schedule/checker/checker_test.go":   "@carol",
+		"tests/integrations/mcs/tso/tso_test.go": "@dave",
+		"tools/docs/gen_test.go":                 "ops@example.com",
+		"server/api/v2/handler_test.go":          "",
+	}
+	for path, want := range cases {
+		if got := strings.Join(matchCodeowners(rules, path), " "); got != want {
+			t.Fatalf("owners of %s = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package issue
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/classify"
+	"github.com/okJiang/flaky-test-cleaner/internal/github"
+	"github.com/okJiang/flaky-test-cleaner/internal/store"
+)
+
+// digestMarkerPrefix identifies the digest issue of a period; the marker sits
+// outside the managed block so it survives edits.
+const digestMarkerPrefix = "<!-- FTC:DIGEST period="
+
+// DigestPeriod returns the period of length d that contains t. Periods are
+// aligned to Mondays 00:00 UTC, so a 7-day period is a calendar week.
+func DigestPeriod(t time.Time, d time.Duration) (time.Time, time.Time) {
+	epoch := time.Date(1970, 1, 5, 0, 0, 0, 0, time.UTC) // a Monday
+	n := t.Sub(epoch) / d
+	start := epoch.Add(n * d)
+	return start, start.Add(d)
+}
+
+type DigestInput struct {
+	Start, End   time.Time
+	Fingerprints []store.FingerprintRecord
+	Summaries    []store.PeriodSummary
+	// Top bounds the list of most frequent failures.
+	Top int
+	// Quiet is how long a fingerprint must not fail to count as fixed, and
+	// how long a gap before a failure makes it a regression; zero disables
+	// both lists.
+	Quiet time.Duration
+}
+
+type DigestData struct {
+	Start, End time.Time
+	// QuietDays is DigestInput.Quiet in days.
+	QuietDays     int
+	TotalFailures int
+	// Tests counts the fingerprints that failed in the period.
+	Tests       int
+	Top         []DigestEntry
+	New         []DigestEntry
+	Regressions []DigestEntry
+	Fixed       []DigestEntry
+	Packages    []PackageCount
+}
+
+type DigestEntry struct {
+	Fingerprint string
+	TestName    string
+	Package     string
+	IssueNumber int
+	// Count is the number of failures in the period.
+	Count    int
+	LastSeen time.Time
+	// QuietFor is the gap before the failure of a regression.
+	QuietFor time.Duration
+}
+
+// QuietDays is QuietFor in whole days.
+func (e DigestEntry) QuietDays() int {
+	return int(e.QuietFor.Hours() / 24)
+}
+
+type PackageCount struct {
+	Package  string
+	Tests    int
+	Failures int
+}
+
+// BuildDigest aggregates the store's view of a period. Infra failures are
+// left out.
+func (m *Manager) BuildDigest(in DigestInput) DigestData {
+	d := DigestData{Start: in.Start, End: in.End, QuietDays: int(in.Quiet.Hours() / 24)}
+	recs := map[string]store.FingerprintRecord{}
+	for _, rec := range in.Fingerprints {
+		if rec.Class == string(classify.ClassInfraFlake) {
+			continue
+		}
+		recs[rec.Fingerprint] = rec
+		if in.Quiet > 0 && !rec.LastSeenAt.IsZero() {
+			if quietAt := rec.LastSeenAt.Add(in.Quiet); !quietAt.Before(in.Start) && quietAt.Before(in.End) {
+				d.Fixed = append(d.Fixed, DigestEntry{Fingerprint: rec.Fingerprint, TestName: rec.TestName, IssueNumber: rec.IssueNumber, LastSeen: rec.LastSeenAt})
+			}
+		}
+	}
+
+	packages := map[string]*PackageCount{}
+	var failed []DigestEntry
+	for _, s := range in.Summaries {
+		rec, ok := recs[s.Fingerprint]
+		if !ok {
+			continue
+		}
+		e := DigestEntry{
+			Fingerprint: s.Fingerprint,
+			TestName:    rec.TestName,
+			Package:     digestPackage(s.Package, m.opts.Owner, m.opts.Repo),
+			IssueNumber: rec.IssueNumber,
+			Count:       s.Count,
+			LastSeen:    rec.LastSeenAt,
+		}
+		failed = append(failed, e)
+		d.TotalFailures += s.Count
+		if !rec.FirstSeenAt.Before(in.Start) {
+			d.New = append(d.New, e)
+		}
+		if gap := s.FirstAt.Sub(s.PreviousAt); in.Quiet > 0 && !s.PreviousAt.IsZero() && gap >= in.Quiet {
+			e.QuietFor = gap
+			d.Regressions = append(d.Regressions, e)
+		}
+		pc := packages[e.Package]
+		if pc == nil {
+			pc = &PackageCount{Package: e.Package}
+			packages[e.Package] = pc
+		}
+		pc.Tests++
+		pc.Failures += s.Count
+	}
+	d.Tests = len(failed)
+
+	sort.SliceStable(failed, func(i, j int) bool {
+		if failed[i].Count != failed[j].Count {
+			return failed[i].Count > failed[j].Count
+		}
+		return failed[i].TestName < failed[j].TestName
+	})
+	d.Top = failed
+	if in.Top > 0 && len(d.Top) > in.Top {
+		d.Top = d.Top[:in.Top]
+	}
+	for _, pc := range packages {
+		d.Packages = append(d.Packages, *pc)
+	}
+	sort.Slice(d.Packages, func(i, j int) bool {
+		a, b := d.Packages[i], d.Packages[j]
+		if a.Tests != b.Tests {
+			return a.Tests > b.Tests
+		}
+		if a.Failures != b.Failures {
+			return a.Failures > b.Failures
+		}
+		return a.Package < b.Package
+	})
+	for _, list := range [][]DigestEntry{d.New, d.Regressions, d.Fixed} {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].TestName < list[j].TestName })
+	}
+	return d
+}
+
+func digestPackage(pkg, owner, repo string) string {
+	if pkg == "" {
+		return "unknown"
+	}
+	return strings.TrimPrefix(relPackage(pkg, owner, repo), "./")
+}
+
+// LastDay is the last day of the period, whose End is exclusive.
+func (d DigestData) LastDay() time.Time {
+	return d.End.Add(-time.Nanosecond)
+}
+
+// DigestTitle is the title of the digest issue of d's period.
+func DigestTitle(d DigestData) string {
+	return fmt.Sprintf("Flaky test digest: %s to %s", d.Start.Format("2006-01-02"), d.LastDay().Format("2006-01-02"))
+}
+
+// RenderDigest renders the body of the digest issue.
+func (m *Manager) RenderDigest(d DigestData) (string, error) {
+	content, err := m.opts.Templates.execute(tmplDigest, d)
+	if err != nil {
+		return "", err
+	}
+	return digestMarker(d.Start) + "\n" + joinBlocks(wrapBlock("DIGEST", content)), nil
+}
+
+func digestMarker(start time.Time) string {
+	return digestMarkerPrefix + start.UTC().Format(time.RFC3339) + " -->"
+}
+
+// PublishDigest creates the digest issue of d's period or updates it in
+// place, and unpins and closes the digests of earlier periods. Pinning the
+// returned issue is left to the caller, since it needs more permissions than
+// the rest.
+func (m *Manager) PublishDigest(ctx context.Context, gh *github.Client, d DigestData) (github.Issue, error) {
+	body, err := m.RenderDigest(d)
+	if err != nil {
+		return github.Issue{}, err
+	}
+	if m.opts.DryRun {
+		return github.Issue{}, nil
+	}
+	if err := gh.EnsureLabels(ctx, m.opts.Owner, m.opts.Repo, []string{LabelDigest}); err != nil {
+		return github.Issue{}, err
+	}
+	existing, err := gh.ListIssues(ctx, m.opts.Owner, m.opts.Repo, github.ListIssuesOptions{Labels: LabelDigest, State: "all", PerPage: 100})
+	if err != nil {
+		return github.Issue{}, err
+	}
+	marker := digestMarker(d.Start)
+	title := DigestTitle(d)
+	var current *github.Issue
+	for i, iss := range existing {
+		if strings.Contains(iss.Body, marker) {
+			current = &existing[i]
+			continue
+		}
+		if iss.State != "open" {
+			continue
+		}
+		// An issue that was never pinned cannot be unpinned; that is fine.
+		_ = gh.UnpinIssue(ctx, iss.NodeID)
+		state, reason := "closed", "completed"
+		if _, err := gh.UpdateIssue(ctx, m.opts.Owner, m.opts.Repo, iss.Number, github.UpdateIssueInput{State: &state, StateReason: &reason}); err != nil {
+			return github.Issue{}, err
+		}
+	}
+	if current == nil {
+		return gh.CreateIssue(ctx, m.opts.Owner, m.opts.Repo, github.CreateIssueInput{
+			Title:  title,
+			Body:   body,
+			Labels: []string{LabelDigest},
+		})
+	}
+	patched := PatchBody(current.Body, body)
+	return gh.UpdateIssue(ctx, m.opts.Owner, m.opts.Repo, current.Number, github.UpdateIssueInput{Title: &title, Body: &patched})
+}
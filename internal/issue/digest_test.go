@@ -0,0 +1,79 @@
+package issue
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/classify"
+	"github.com/okJiang/flaky-test-cleaner/internal/store"
+)
+
+func TestDigestPeriod(t *testing.T) {
+	// 2024-06-05 is a Wednesday.
+	start, end := DigestPeriod(time.Date(2024, 6, 5, 13, 0, 0, 0, time.UTC), 7*24*time.Hour)
+	if !start.Equal(time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected period %s to %s", start, end)
+	}
+}
+
+func TestBuildDigest(t *testing.T) {
+	start := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 7)
+	quiet := 30 * 24 * time.Hour
+	m := NewManager(Options{Owner: "tikv", Repo: "pd"})
+	d := m.BuildDigest(DigestInput{
+		Start: start,
+		End:   end,
+		Fingerprints: []store.FingerprintRecord{
+			{Fingerprint: "a", TestName: "TestOld", IssueNumber: 1, FirstSeenAt: start.AddDate(0, -3, 0), LastSeenAt: start.Add(time.Hour)},
+			{Fingerprint: "b", TestName: "TestNew", IssueNumber: 2, FirstSeenAt: start.Add(2 * time.Hour), LastSeenAt: start.Add(3 * time.Hour)},
+			{Fingerprint: "c", TestName: "TestBack", FirstSeenAt: start.AddDate(0, -6, 0), LastSeenAt: start.Add(time.Hour)},
+			{Fingerprint: "d", TestName: "TestGone", IssueNumber: 4, LastSeenAt: start.Add(-quiet + 24*time.Hour)},
+			{Fingerprint: "e", TestName: "TestInfra", Class: string(classify.ClassInfraFlake), LastSeenAt: start.Add(time.Hour)},
+		},
+		Summaries: []store.PeriodSummary{
+			{Fingerprint: "a", Count: 5, FirstAt: start.Add(time.Hour), PreviousAt: start.AddDate(0, 0, -2), Package: "github.com/tikv/pd/pkg/foo"},
+			{Fingerprint: "b", Count: 2, FirstAt: start.Add(2 * time.Hour), Package: "github.com/tikv/pd/pkg/foo"},
+			{Fingerprint: "c", Count: 1, FirstAt: start.Add(time.Hour), PreviousAt: start.AddDate(0, -2, 0)},
+			{Fingerprint: "e", Count: 9, FirstAt: start.Add(time.Hour)},
+		},
+		Top:   2,
+		Quiet: quiet,
+	})
+	names := func(list []DigestEntry) string {
+		var out []string
+		for _, e := range list {
+			out = append(out, e.TestName)
+		}
+		return strings.Join(out, ",")
+	}
+	if d.TotalFailures != 8 || d.Tests != 3 {
+		t.Fatalf("unexpected totals %d failures of %d tests", d.TotalFailures, d.Tests)
+	}
+	if got := names(d.Top); got != "TestOld,TestNew" {
+		t.Fatalf("top = %s", got)
+	}
+	if got := names(d.New); got != "TestNew" {
+		t.Fatalf("new = %s", got)
+	}
+	if got := names(d.Regressions); got != "TestBack" {
+		t.Fatalf("regressions = %s", got)
+	}
+	if got := names(d.Fixed); got != "TestGone" {
+		t.Fatalf("fixed = %s", got)
+	}
+	if len(d.Packages) != 2 || d.Packages[0] != (PackageCount{Package: "pkg/foo", Tests: 2, Failures: 7}) {
+		t.Fatalf("unexpected packages %+v", d.Packages)
+	}
+
+	body, err := m.RenderDigest(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{digestMarker(start), "2024-06-03 to 2024-06-09", "| TestOld | pkg/foo | 5 |", "`TestBack` in `unknown`: 1 failures after 61 quiet days"} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("digest body lacks %q:\n%s", want, body)
+		}
+	}
+}
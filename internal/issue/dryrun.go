@@ -0,0 +1,222 @@
+package issue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/github"
+)
+
+// Kinds of recorded dry-run actions.
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionAdopt  = "adopt"
+	ActionRecur  = "recurrence"
+	ActionReopen = "reopen"
+	ActionClose  = "close"
+	ActionMerge  = "merge"
+)
+
+// Action is everything a write would do to one issue, as recorded in
+// dry-run.
+type Action struct {
+	Kind        string `json:"kind"`
+	IssueNumber int    `json:"issue_number,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// Title and Body are what the issue would be set to; PreviousTitle is
+	// the current title when it would change.
+	Title         string   `json:"title,omitempty"`
+	PreviousTitle string   `json:"previous_title,omitempty"`
+	Body          string   `json:"body,omitempty"`
+	Labels        []string `json:"labels,omitempty"`
+	AddLabels     []string `json:"add_labels,omitempty"`
+	RemoveLabels  []string `json:"remove_labels,omitempty"`
+	// Assignees are candidates; only repository collaborators among them
+	// would be assigned.
+	Assignees []string        `json:"assignees,omitempty"`
+	Comments  []ActionComment `json:"comments,omitempty"`
+	// State and StateReason are the lifecycle transition, if any.
+	State       string `json:"state,omitempty"`
+	StateReason string `json:"state_reason,omitempty"`
+	// Diff is a unified diff of the current issue (or comment) body against
+	// the planned one.
+	Diff string `json:"diff,omitempty"`
+}
+
+// ActionComment is a comment to post, or to edit when CommentID is set.
+type ActionComment struct {
+	CommentID int64  `json:"comment_id,omitempty"`
+	Body      string `json:"body"`
+}
+
+// Recorder writes dry-run actions to a directory, one Markdown and one JSON
+// file per action, numbered in the order they were planned.
+type Recorder struct {
+	dir string
+	n   int
+}
+
+func NewRecorder(dir string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Recorder{dir: dir}, nil
+}
+
+func (r *Recorder) Dir() string {
+	return r.dir
+}
+
+func (r *Recorder) Record(a Action) error {
+	r.n++
+	target := "new"
+	if a.IssueNumber != 0 {
+		target = fmt.Sprintf("issue-%d", a.IssueNumber)
+	} else if a.Fingerprint != "" {
+		target = "new-" + a.Fingerprint[:min(len(a.Fingerprint), 12)]
+	}
+	base := filepath.Join(r.dir, fmt.Sprintf("%03d-%s-%s", r.n, a.Kind, target))
+	data, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(base+".json", append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.WriteFile(base+".md", []byte(a.markdown()), 0o644)
+}
+
+func (a Action) markdown() string {
+	var b strings.Builder
+	if a.IssueNumber != 0 {
+		fmt.Fprintf(&b, "# %s #%d\n\n", a.Kind, a.IssueNumber)
+	} else {
+		fmt.Fprintf(&b, "# %s\n\n", a.Kind)
+	}
+	if a.Fingerprint != "" {
+		fmt.Fprintf(&b, "- Fingerprint: `%s`\n", a.Fingerprint)
+	}
+	if a.Title != "" {
+		fmt.Fprintf(&b, "- Title: %s\n", code(a.Title))
+	}
+	if a.PreviousTitle != "" {
+		fmt.Fprintf(&b, "- Previous title: %s\n", code(a.PreviousTitle))
+	}
+	for _, l := range []struct {
+		name   string
+		labels []string
+	}{{"Labels", a.Labels}, {"Add labels", a.AddLabels}, {"Remove labels", a.RemoveLabels}, {"Assignees (collaborators only)", a.Assignees}} {
+		if len(l.labels) > 0 {
+			fmt.Fprintf(&b, "- %s: %s\n", l.name, strings.Join(l.labels, ", "))
+		}
+	}
+	if a.State != "" {
+		state := a.State
+		if a.StateReason != "" {
+			state += " (" + a.StateReason + ")"
+		}
+		fmt.Fprintf(&b, "- State: %s\n", state)
+	}
+	if a.Diff != "" {
+		b.WriteString("\n## Diff\n\n" + fenceAs("diff", a.Diff) + "\n")
+	}
+	if a.Body != "" {
+		b.WriteString("\n## Body\n\n" + fenceAs("markdown", a.Body) + "\n")
+	}
+	for _, c := range a.Comments {
+		if c.CommentID != 0 {
+			fmt.Fprintf(&b, "\n## Edit comment %d\n\n", c.CommentID)
+		} else {
+			b.WriteString("\n## New comment\n\n")
+		}
+		b.WriteString(fenceAs("markdown", c.Body) + "\n")
+	}
+	return b.String()
+}
+
+// fenceAs is fence with an info string.
+func fenceAs(lang, s string) string {
+	f := fence(s)
+	i := strings.IndexByte(f, '\n')
+	return f[:i] + lang + f[i:]
+}
+
+// record hands a to the Recorder, if any.
+func (m *Manager) record(a Action) error {
+	if m.opts.Recorder == nil {
+		return nil
+	}
+	return m.opts.Recorder.Record(a)
+}
+
+// recordApply records what Apply would do, reading the current issue to diff
+// against.
+func (m *Manager) recordApply(ctx context.Context, gh *github.Client, ch PlannedChange) error {
+	if m.opts.Recorder == nil {
+		return nil
+	}
+	if ch.Create {
+		return m.record(Action{
+			Kind:        ActionCreate,
+			Fingerprint: ch.Fingerprint,
+			Title:       ch.Title,
+			Body:        ch.Body,
+			Labels:      ch.Labels,
+			Assignees:   m.assignees(nil, ch.Assignees),
+		})
+	}
+	current, err := gh.GetIssue(ctx, m.opts.Owner, m.opts.Repo, ch.IssueNumber)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(current.Labels))
+	for _, l := range current.Labels {
+		names = append(names, l.Name)
+	}
+	a := Action{
+		Kind:        ActionUpdate,
+		IssueNumber: ch.IssueNumber,
+		Fingerprint: ch.Fingerprint,
+		Labels:      ch.Labels,
+		Assignees:   m.assignees(current.Assignees, ch.Assignees),
+	}
+	a.AddLabels, a.RemoveLabels = ReconcileLabels(names, ch.Labels)
+	if FingerprintFromBody(current.Body) == "" {
+		// Adopted issues only get our comment; see applyAdopted.
+		a.Kind = ActionAdopt
+		comments, err := gh.ListIssueComments(ctx, m.opts.Owner, m.opts.Repo, ch.IssueNumber)
+		if err != nil {
+			return err
+		}
+		for _, c := range comments {
+			if FingerprintFromBody(c.Body) == ch.Fingerprint {
+				body := PatchBody(c.Body, ch.Body)
+				a.Comments = []ActionComment{{CommentID: c.ID, Body: body}}
+				a.Diff = unifiedDiff(fmt.Sprintf("comment %d", c.ID), "planned", c.Body, body)
+				return m.record(a)
+			}
+		}
+		a.Comments = []ActionComment{{Body: ch.Body}}
+		return m.record(a)
+	}
+	a.Title = ch.Title
+	if current.Title != ch.Title {
+		a.PreviousTitle = current.Title
+	}
+	a.Body = PatchBody(current.Body, ch.Body)
+	a.Diff = unifiedDiff(fmt.Sprintf("issue #%d", ch.IssueNumber), "planned", current.Body, a.Body)
+	return m.record(a)
+}
+
+// assignees returns the owners assign would consider.
+func (m *Manager) assignees(current []github.User, owners []string) []string {
+	if !m.opts.AutoAssign || len(current) > 0 {
+		return nil
+	}
+	return owners
+}
@@ -0,0 +1,78 @@
+package issue
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	if got := unifiedDiff("a", "b", "x\ny\n", "x\ny\n"); got != "" {
+		t.Fatalf("expected no diff for equal texts, got %q", got)
+	}
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	b := "1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n11\n12\n13\n"
+	want := `--- issue #1
++++ planned
+@@ -2,7 +2,7 @@
+ 2
+ 3
+ 4
+-5
++five
+ 6
+ 7
+ 8
+@@ -10,3 +10,4 @@
+ 10
+ 11
+ 12
++13
+`
+	if got := unifiedDiff("issue #1", "planned", a, b); got != want {
+		t.Fatalf("unexpected diff:\n%s", got)
+	}
+	want = "--- a\n+++ b\n@@ -0,0 +1 @@\n+x\n"
+	if got := unifiedDiff("a", "b", "", "x\n"); got != want {
+		t.Fatalf("unexpected diff from empty:\n%s", got)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	dir := t.TempDir()
+	rec, err := NewRecorder(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager(Options{Owner: "tikv", Repo: "pd", DryRun: true, Recorder: rec})
+	ctx := context.Background()
+	if _, err := m.Apply(ctx, nil, PlannedChange{Create: true, Fingerprint: "0123456789abcdef", Title: "flaky: TestA", Body: "body", Labels: []string{LabelFlakyTest}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.CloseQuiet(ctx, nil, 7, time.Now().Add(-40*24*time.Hour), 30*24*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "001-create-new-0123456789ab.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var a Action
+	if err := json.Unmarshal(data, &a); err != nil {
+		t.Fatal(err)
+	}
+	if a.Kind != ActionCreate || a.Title != "flaky: TestA" || a.Body != "body" {
+		t.Fatalf("unexpected create action: %+v", a)
+	}
+	md, err := os.ReadFile(filepath.Join(dir, "002-close-issue-7.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(md), "- State: closed (completed)") || !strings.Contains(string(md), "## New comment") {
+		t.Fatalf("unexpected close action:\n%s", md)
+	}
+}
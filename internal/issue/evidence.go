@@ -0,0 +1,75 @@
+package issue
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/store"
+)
+
+// EvidenceGate is the evidence a fingerprint needs before an issue is opened
+// for it; until then it is a candidate.
+type EvidenceGate struct {
+	MinOccurrences  int
+	MinDistinctSHAs int
+	// Window bounds the occurrences counted to the most recent ones; zero
+	// counts all of them.
+	Window time.Duration
+	// Branch, when set, requires at least one occurrence on it, typically
+	// the default branch.
+	Branch string
+}
+
+// Since is where the gate's window starts at now.
+func (g EvidenceGate) Since(now time.Time) time.Time {
+	if g.Window <= 0 {
+		return time.Time{}
+	}
+	return now.Add(-g.Window)
+}
+
+// Met reports whether e passes every gate.
+func (g EvidenceGate) Met(e store.Evidence) bool {
+	return e.Occurrences >= g.MinOccurrences && e.DistinctSHAs >= g.MinDistinctSHAs && (g.Branch == "" || e.BranchOccurrences > 0)
+}
+
+// Progress describes e against the gates, e.g. "occurrences 1/3, commits
+// 1/2, on master no".
+func (g EvidenceGate) Progress(e store.Evidence) string {
+	parts := []string{
+		fmt.Sprintf("occurrences %d/%d", e.Occurrences, max(g.MinOccurrences, 1)),
+		fmt.Sprintf("commits %d/%d", e.DistinctSHAs, max(g.MinDistinctSHAs, 1)),
+	}
+	if g.Branch != "" {
+		on := "no"
+		if e.BranchOccurrences > 0 {
+			on = "yes"
+		}
+		parts = append(parts, fmt.Sprintf("on %s %s", g.Branch, on))
+	}
+	if g.Window > 0 {
+		parts = append(parts, fmt.Sprintf("within %s", formatWindow(g.Window)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// HoldBack says why a fingerprint without an issue does not get one, or ""
+// when it does: the policy must file it and the evidence must pass the gate.
+// candidate is set when only the evidence is lacking.
+func (m *Manager) HoldBack(d Decision, e store.Evidence) (reason string, candidate bool) {
+	if !d.OpensIssue() {
+		return fmt.Sprintf("policy %s: %s", d.Action, d.Reason), false
+	}
+	if !m.opts.Gate.Met(e) {
+		return "candidate: " + m.opts.Gate.Progress(e), true
+	}
+	return "", false
+}
+
+func formatWindow(d time.Duration) string {
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	return d.String()
+}
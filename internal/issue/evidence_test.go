@@ -0,0 +1,70 @@
+package issue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/classify"
+	"github.com/okJiang/flaky-test-cleaner/internal/extract"
+	"github.com/okJiang/flaky-test-cleaner/internal/store"
+)
+
+func TestEvidenceGate(t *testing.T) {
+	gate := EvidenceGate{MinOccurrences: 3, MinDistinctSHAs: 2, Window: 14 * 24 * time.Hour, Branch: "master"}
+	e := store.Evidence{Occurrences: 3, DistinctSHAs: 1}
+	if gate.Met(e) {
+		t.Fatalf("expected %+v to miss the gate", e)
+	}
+	if got, want := gate.Progress(e), "occurrences 3/3, commits 1/2, on master no, within 14d"; got != want {
+		t.Fatalf("Progress = %q, want %q", got, want)
+	}
+	e = store.Evidence{Occurrences: 4, DistinctSHAs: 2, BranchOccurrences: 1}
+	if !gate.Met(e) {
+		t.Fatalf("expected %+v to pass the gate", e)
+	}
+	if !(EvidenceGate{}).Met(store.Evidence{}) || !(EvidenceGate{}).Since(time.Now()).IsZero() {
+		t.Fatalf("expected the zero gate to let everything through")
+	}
+}
+
+func TestPlanIssueUpdateHoldsBackCandidates(t *testing.T) {
+	seen := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	mgr := NewManager(Options{Owner: "tikv", Repo: "pd", Gate: EvidenceGate{MinOccurrences: 2}})
+	in := PlanInput{
+		Fingerprint:    store.FingerprintRecord{Fingerprint: "abc", TestName: "TestFoo", FirstSeenAt: seen, LastSeenAt: seen},
+		Occurrences:    []extract.Occurrence{{RunID: 1, TestName: "TestFoo", ErrorSignature: "panic: boom", OccurredAt: seen}},
+		Classification: classify.Result{Class: classify.ClassFlakyTest, Confidence: 0.9},
+		Evidence:       store.Evidence{Occurrences: 1, DistinctSHAs: 1},
+	}
+	change, err := mgr.PlanIssueUpdate(in)
+	if err != nil || !change.Noop || !change.Candidate || change.Reason != "candidate: occurrences 1/2, commits 1/1" {
+		t.Fatalf("expected a candidate, got %+v err=%v", change, err)
+	}
+
+	in.Fingerprint.IssueNumber = 7
+	change, err = mgr.PlanIssueUpdate(in)
+	if err != nil || change.Noop || change.Candidate {
+		t.Fatalf("expected an existing issue to be updated regardless of the gate, got %+v err=%v", change, err)
+	}
+
+	in.Fingerprint.IssueNumber = 0
+	in.Evidence = store.Evidence{Occurrences: 2, DistinctSHAs: 1}
+	change, err = mgr.PlanIssueUpdate(in)
+	if err != nil || !change.Create {
+		t.Fatalf("expected an issue once the gate is met, got %+v err=%v", change, err)
+	}
+}
+
+func TestHoldBack(t *testing.T) {
+	mgr := NewManager(Options{Owner: "tikv", Repo: "pd", Gate: EvidenceGate{MinOccurrences: 2, Branch: "master"}})
+	flaky := Decision{Action: DecisionFlaky}
+	if reason, candidate := mgr.HoldBack(flaky, store.Evidence{Occurrences: 1, DistinctSHAs: 1, BranchOccurrences: 1}); !candidate || reason != "candidate: occurrences 1/2, commits 1/1, on master yes" {
+		t.Fatalf("expected a candidate, got %q %v", reason, candidate)
+	}
+	if reason, candidate := mgr.HoldBack(Decision{Action: DecisionRecord, Reason: "any other result"}, store.Evidence{Occurrences: 5, DistinctSHAs: 5, BranchOccurrences: 5}); candidate || reason != "policy record: any other result" {
+		t.Fatalf("expected the policy to hold it back, got %q %v", reason, candidate)
+	}
+	if reason, _ := mgr.HoldBack(flaky, store.Evidence{Occurrences: 2, DistinctSHAs: 1, BranchOccurrences: 1}); reason != "" {
+		t.Fatalf("expected an issue, got %q", reason)
+	}
+}
@@ -0,0 +1,113 @@
+package issue
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/github"
+)
+
+// reUnstableTitle only matches wording that calls a test unstable: a title
+// that merely says a test fails may well be a regression report.
+var reUnstableTitle = regexp.MustCompile(`(?i)\b(unstable|flaky|flakey|intermittent(ly)?)\b`)
+
+// HumanMatches are human-filed issues that mention a test. Strong is an open
+// issue whose title names the test as unstable; it is adopted as the tracking
+// issue. Weak matches are only cross-referenced.
+type HumanMatches struct {
+	Strong *github.Issue
+	Weak   []github.Issue
+}
+
+// FindHumanIssues searches open and closed issues not managed by the tool
+// for testName, narrowed by Options.HumanIssueQuery. Results are cached per
+// test for the lifetime of the Manager.
+func (m *Manager) FindHumanIssues(ctx context.Context, gh *github.Client, testName string) (HumanMatches, error) {
+	name := strings.SplitN(testName, "/", 2)[0]
+	if name == "" {
+		return HumanMatches{}, nil
+	}
+	if cached, ok := m.human[name]; ok {
+		return cached, nil
+	}
+	query := strings.TrimSpace(fmt.Sprintf(`repo:%s/%s is:issue "%s" in:title,body %s`, m.opts.Owner, m.opts.Repo, name, m.opts.HumanIssueQuery))
+	items, err := gh.SearchIssues(ctx, query, 20)
+	if err != nil {
+		return HumanMatches{}, err
+	}
+	res := matchHumanIssues(name, items)
+	if m.human == nil {
+		m.human = map[string]HumanMatches{}
+	}
+	m.human[name] = res
+	return res, nil
+}
+
+// Adoptable returns the strong match a fingerprint without an issue adopts,
+// or nil when the policy decision would not open an issue for it.
+func (h HumanMatches) Adoptable(d Decision) *github.Issue {
+	if !d.OpensIssue() {
+		return nil
+	}
+	return h.Strong
+}
+
+func matchHumanIssues(testName string, items []github.Issue) HumanMatches {
+	reTest := regexp.MustCompile(`\b` + regexp.QuoteMeta(testName) + `\b`)
+	var res HumanMatches
+	var strong []github.Issue
+	for _, iss := range items {
+		if hasLabel(iss, LabelAIManaged) || hasLabel(iss, LabelDigest) {
+			continue
+		}
+		titled := reTest.MatchString(iss.Title)
+		if !titled && !reTest.MatchString(iss.Body) {
+			continue
+		}
+		if titled && iss.State == "open" && reUnstableTitle.MatchString(iss.Title) {
+			strong = append(strong, iss)
+			continue
+		}
+		res.Weak = append(res.Weak, iss)
+	}
+	for i := range strong {
+		if res.Strong == nil || strong[i].Number < res.Strong.Number {
+			res.Strong = &strong[i]
+		}
+	}
+	for _, iss := range strong {
+		if iss.Number != res.Strong.Number {
+			res.Weak = append(res.Weak, iss)
+		}
+	}
+	return res
+}
+
+func hasLabel(iss github.Issue, name string) bool {
+	for _, l := range iss.Labels {
+		if l.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// applyAdopted tracks a fingerprint on a human-filed issue: the managed
+// blocks live in a comment of ours, and the issue body and title are left to
+// their authors.
+func (m *Manager) applyAdopted(ctx context.Context, gh *github.Client, ch PlannedChange) error {
+	comments, err := gh.ListIssueComments(ctx, m.opts.Owner, m.opts.Repo, ch.IssueNumber)
+	if err != nil {
+		return err
+	}
+	for _, c := range comments {
+		if FingerprintFromBody(c.Body) == ch.Fingerprint {
+			_, err := gh.UpdateComment(ctx, m.opts.Owner, m.opts.Repo, c.ID, PatchBody(c.Body, ch.Body))
+			return err
+		}
+	}
+	_, err = gh.CreateComment(ctx, m.opts.Owner, m.opts.Repo, ch.IssueNumber, ch.Body)
+	return err
+}
@@ -2,6 +2,8 @@ package issue
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"strings"
 	"time"
@@ -16,70 +18,188 @@ type Options struct {
 	Owner  string
 	Repo   string
 	DryRun bool
+	// MinUpdateInterval is the shortest time between two edits of the same
+	// issue; changes made sooner wait for a later scan.
+	MinUpdateInterval time.Duration
+	// QuietPeriod, SpikeFactor and CommentInterval tune recurrence comments;
+	// see DetectRecurrence and PostRecurrence.
+	QuietPeriod     time.Duration
+	SpikeFactor     float64
+	CommentInterval time.Duration
+	// HumanIssueQuery narrows the search for human-filed issues, e.g.
+	// `label:type/ci`.
+	HumanIssueQuery string
+	// Templates renders titles, bodies and comments; nil means the embedded
+	// defaults.
+	Templates *Templates
+	// AutoAssign assigns suggested owners who are repository collaborators
+	// to issues nobody is assigned to.
+	AutoAssign bool
+	// Profile holds per-repository settings such as the reproduction
+	// command; the zero value means DefaultRepoProfile.
+	Profile RepoProfile
+	// Policy decides which fingerprints get issues; no rules means
+	// DefaultPolicy with DefaultConfidenceThreshold and no triage issues.
+	Policy Policy
+	// Gate holds back new issues until there is enough evidence; the zero
+	// value lets every issue through.
+	Gate EvidenceGate
+	// Recorder receives, in dry-run, every write that would have been made;
+	// nil means dry-run writes nothing.
+	Recorder *Recorder
 }
 
 type Manager struct {
 	opts Options
+
+	// managed caches ai-managed issues by fingerprint for FindIssue; human
+	// caches FindHumanIssues by test name.
+	managed map[string]github.Issue
+	human   map[string]HumanMatches
+	// codeowners caches CODEOWNERS rules by commit, authors recent
+	// committers by commit and path.
+	codeowners map[string][]codeownersRule
+	authors    map[string][]string
 }
 
-func NewManager(opts Options) *Manager { return &Manager{opts: opts} }
+func NewManager(opts Options) *Manager {
+	if opts.Templates == nil {
+		opts.Templates = DefaultTemplates()
+	}
+	if opts.Profile.Repro.tmpl == nil {
+		opts.Profile = DefaultRepoProfile()
+	}
+	if len(opts.Policy.Rules) == 0 {
+		opts.Policy = DefaultPolicy(DefaultConfidenceThreshold, false)
+	}
+	return &Manager{opts: opts}
+}
 
 type PlanInput struct {
 	Fingerprint    store.FingerprintRecord
 	Occurrences    []extract.Occurrence
 	Classification classify.Result
+	// Attempts holds the per-attempt job outcomes of each occurrence's run.
+	Attempts map[int64][]store.JobAttempt
+	// Executions counts passes and failures of the test, including baseline
+	// samples of successful runs.
+	Executions store.TestExecutionStats
+	// Related are human-filed issues that may track the same failure.
+	Related []github.Issue
+	// Stats aggregates every occurrence of the fingerprint, not only
+	// Occurrences.
+	Stats store.OccurrenceStats
+	// Owners are the suggested owners of the test, see SuggestOwners.
+	Owners Owners
+	// Evidence counts the occurrences within Options.Gate's window.
+	Evidence store.Evidence
 }
 
 type PlannedChange struct {
-	Noop        bool
+	Noop bool
+	// Reason says why a change is a no-op.
+	Reason string
+	// Candidate is set when an issue is held back for lack of evidence.
+	Candidate   bool
 	Create      bool
 	IssueNumber int
+	Fingerprint string
 	Title       string
 	Body        string
 	Labels      []string
+	// ContentHash covers the title, labels and body minus volatile timestamps;
+	// the caller stores it once the change is applied.
+	ContentHash string
+	// Assignees are the suggested owners Apply may assign, see
+	// Options.AutoAssign.
+	Assignees []string
 }
 
 func (m *Manager) PlanIssueUpdate(in PlanInput) (PlannedChange, error) {
 	if len(in.Occurrences) == 0 {
-		return PlannedChange{Noop: true}, nil
+		return PlannedChange{Noop: true, Reason: "no occurrences"}, nil
 	}
 	shortSig := summarizeSignature(in.Occurrences[0].ErrorSignature)
 	name := in.Fingerprint.TestName
 	if name == "" {
 		name = in.Occurrences[0].TestName
 	}
+	repro, err := m.repro(name, in.Occurrences)
+	if err != nil {
+		return PlannedChange{}, err
+	}
 	if name == "" {
 		name = "unknown-test"
 	}
 	if shortSig == "" {
 		shortSig = "unknown-error"
 	}
-	title := fmt.Sprintf("[flaky] %s — %s", name, shortSig)
-	labels := defaultLabels(in.Classification)
-	body := buildBody(in, labels)
+	decision := m.Decide(in.Classification, in.Stats)
+	if in.Fingerprint.IssueNumber == 0 {
+		if reason, candidate := m.HoldBack(decision, in.Evidence); reason != "" {
+			return PlannedChange{Noop: true, Candidate: candidate, Reason: reason}, nil
+		}
+	}
+	labels := decisionLabels(decision, in.Classification)
+	data := issueData(in, name, shortSig, labels)
+	data.Repro = repro
+	data.Decision = decision
+	title, err := m.opts.Templates.Title(data)
+	if err != nil {
+		return PlannedChange{}, err
+	}
+	// The content hash is taken over a rendering without the update time.
+	stable, err := m.opts.Templates.Body(data)
+	if err != nil {
+		return PlannedChange{}, err
+	}
+	data.UpdatedAt = time.Now()
+	body, err := m.opts.Templates.Body(data)
+	if err != nil {
+		return PlannedChange{}, err
+	}
+	hash := contentHash(title, labels, stable)
 
 	if in.Fingerprint.IssueNumber == 0 {
 		return PlannedChange{
-			Create: true,
-			Title:  title,
-			Body:   body,
-			Labels: labels,
+			Create:      true,
+			Fingerprint: in.Fingerprint.Fingerprint,
+			Title:       title,
+			Body:        body,
+			Labels:      labels,
+			ContentHash: hash,
+			Assignees:   in.Owners.Assignees(),
+		}, nil
+	}
+	if hash == in.Fingerprint.IssueContentHash {
+		return PlannedChange{Noop: true, Reason: "unchanged", IssueNumber: in.Fingerprint.IssueNumber}, nil
+	}
+	if last := in.Fingerprint.IssueUpdatedAt; !last.IsZero() && time.Since(last) < m.opts.MinUpdateInterval {
+		return PlannedChange{
+			Noop:        true,
+			Reason:      fmt.Sprintf("last edited %s ago", time.Since(last).Round(time.Minute)),
+			IssueNumber: in.Fingerprint.IssueNumber,
 		}, nil
 	}
 	return PlannedChange{
 		IssueNumber: in.Fingerprint.IssueNumber,
+		Fingerprint: in.Fingerprint.Fingerprint,
 		Title:       title,
 		Body:        body,
 		Labels:      labels,
+		ContentHash: hash,
+		Assignees:   in.Owners.Assignees(),
 	}, nil
 }
 
+// Apply writes a planned change and returns the issue it wrote. When only
+// the assignment failed, the issue number comes with an *AssignError.
 func (m *Manager) Apply(ctx context.Context, gh *github.Client, ch PlannedChange) (int, error) {
 	if ch.Noop {
 		return 0, nil
 	}
 	if m.opts.DryRun {
-		return 0, nil
+		return 0, m.recordApply(ctx, gh, ch)
 	}
 	if err := gh.EnsureLabels(ctx, m.opts.Owner, m.opts.Repo, ch.Labels); err != nil {
 		return 0, err
@@ -93,80 +213,148 @@ func (m *Manager) Apply(ctx context.Context, gh *github.Client, ch PlannedChange
 		if err != nil {
 			return 0, err
 		}
-		return created.Number, nil
+		return created.Number, m.assign(ctx, gh, created.Number, created.Assignees, ch.Assignees)
 	}
-	_, err := gh.UpdateIssue(ctx, m.opts.Owner, m.opts.Repo, ch.IssueNumber, github.UpdateIssueInput{
-		Title:  &ch.Title,
-		Body:   &ch.Body,
-		Labels: ch.Labels,
-	})
+	current, err := gh.GetIssue(ctx, m.opts.Owner, m.opts.Repo, ch.IssueNumber)
 	if err != nil {
 		return 0, err
 	}
-	return ch.IssueNumber, nil
+	if FingerprintFromBody(current.Body) == "" {
+		// A human-filed issue we adopted: never rewrite its body or title.
+		if err := m.applyAdopted(ctx, gh, ch); err != nil {
+			return 0, err
+		}
+	} else {
+		body := PatchBody(current.Body, ch.Body)
+		_, err = gh.UpdateIssue(ctx, m.opts.Owner, m.opts.Repo, ch.IssueNumber, github.UpdateIssueInput{
+			Title: &ch.Title,
+			Body:  &body,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	names := make([]string, 0, len(current.Labels))
+	for _, l := range current.Labels {
+		names = append(names, l.Name)
+	}
+	add, remove := ReconcileLabels(names, ch.Labels)
+	if len(add) > 0 {
+		if err := gh.AddLabels(ctx, m.opts.Owner, m.opts.Repo, ch.IssueNumber, add); err != nil {
+			return 0, err
+		}
+	}
+	for _, l := range remove {
+		if err := gh.RemoveLabel(ctx, m.opts.Owner, m.opts.Repo, ch.IssueNumber, l); err != nil {
+			return 0, err
+		}
+	}
+	return ch.IssueNumber, m.assign(ctx, gh, ch.IssueNumber, current.Assignees, ch.Assignees)
+}
+
+func (m *Manager) CloseMerged(ctx context.Context, gh *github.Client, number, into int, fingerprint string) error {
+	if number == 0 || number == into {
+		return nil
+	}
+	body, err := m.opts.Templates.execute(tmplMerged, MergedData{Into: into, Fingerprint: fingerprint})
+	if err != nil {
+		return err
+	}
+	if m.opts.DryRun {
+		return m.record(Action{Kind: ActionMerge, IssueNumber: number, Fingerprint: fingerprint, Comments: []ActionComment{{Body: body}}, State: "closed", StateReason: "not_planned"})
+	}
+	if _, err := gh.CreateComment(ctx, m.opts.Owner, m.opts.Repo, number, body); err != nil {
+		return err
+	}
+	state, reason := "closed", "not_planned"
+	_, err = gh.UpdateIssue(ctx, m.opts.Owner, m.opts.Repo, number, github.UpdateIssueInput{
+		State:       &state,
+		StateReason: &reason,
+	})
+	return err
+}
+
+// Priority ranks a flaky test by its failure rate when a baseline is known and
+// by the number of recent occurrences otherwise.
+func Priority(execs store.TestExecutionStats, occurrences int) string {
+	if execs.Executions() >= 20 {
+		switch rate := execs.FailureRate(); {
+		case rate >= 0.05:
+			return "high"
+		case rate >= 0.01:
+			return "medium"
+		default:
+			return "low"
+		}
+	}
+	switch {
+	case occurrences >= 5:
+		return "high"
+	case occurrences >= 2:
+		return "medium"
+	default:
+		return "low"
+	}
+}
+
+// decisionLabels labels an issue by the policy decision; issues the policy
+// would not have opened keep the labels of their class.
+func decisionLabels(d Decision, res classify.Result) []string {
+	switch d.Action {
+	case DecisionFlaky:
+		return []string{LabelAIManaged, LabelFlakyTest}
+	case DecisionTriage:
+		return []string{LabelAIManaged, LabelNeedsTriage}
+	}
+	return defaultLabels(res)
 }
 
 func defaultLabels(res classify.Result) []string {
 	labels := []string{
-		"flaky-test-cleaner/ai-managed",
+		LabelAIManaged,
 	}
 	switch res.Class {
 	case classify.ClassFlakyTest:
-		labels = append(labels, "flaky-test-cleaner/flaky-test")
+		labels = append(labels, LabelFlakyTest)
 	case classify.ClassUnknown:
-		labels = append(labels, "flaky-test-cleaner/needs-triage")
+		labels = append(labels, LabelNeedsTriage)
 	case classify.ClassLikelyRegression:
-		labels = append(labels, "flaky-test-cleaner/needs-triage")
+		labels = append(labels, LabelNeedsTriage)
 	}
 	return labels
 }
 
-func buildBody(in PlanInput, labels []string) string {
+// contentHash ignores the scan timestamp so that only evidence,
+// classification or label changes cause an edit.
+func contentHash(title string, labels []string, stableBody string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s", title, strings.Join(labels, ","), stableBody)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func issueData(in PlanInput, name, shortSig string, labels []string) IssueData {
 	firstSeen := in.Fingerprint.FirstSeenAt
 	lastSeen := in.Fingerprint.LastSeenAt
 	if firstSeen.IsZero() || lastSeen.IsZero() {
 		firstSeen, lastSeen = occurrenceRange(in.Occurrences)
 	}
-
-	summary := fmt.Sprintf("## Summary\n\n- Classification: **%s** (confidence %.2f)\n- First seen: %s\n- Last seen: %s\n",
-		in.Classification.Class,
-		in.Classification.Confidence,
-		formatTime(firstSeen),
-		formatTime(lastSeen),
-	)
-
-	evidence := "## Evidence\n\n| Run | Workflow | Job | Commit | Test | Error Signature |\n| --- | --- | --- | --- | --- | --- |\n"
-	for _, occ := range in.Occurrences {
-		evidence += fmt.Sprintf("| [%d](%s) | %s | %s | %s | %s | %s |\n",
-			occ.RunID, occ.RunURL, occ.Workflow, occ.JobName, shortSHA(occ.HeadSHA), safe(occ.TestName), summarizeSignature(occ.ErrorSignature),
-		)
-	}
-
-	excerpts := "## Log Excerpts\n"
-	for _, occ := range in.Occurrences {
-		if occ.Excerpt == "" {
-			continue
-		}
-		excerpts += fmt.Sprintf("\n<details>\n<summary>Run %d — %s</summary>\n\n````\n%s\n````\n</details>\n",
-			occ.RunID, safe(occ.JobName), occ.Excerpt,
-		)
+	return IssueData{
+		Fingerprint:    in.Fingerprint,
+		TestName:       name,
+		ShortSignature: shortSig,
+		Occurrences:    in.Occurrences,
+		Excerpts:       excerptOccurrences(in.Occurrences),
+		Classification: in.Classification,
+		Executions:     in.Executions,
+		Priority:       Priority(in.Executions, len(in.Occurrences)),
+		FirstSeen:      firstSeen,
+		LastSeen:       lastSeen,
+		Labels:         labels,
+		Related:        in.Related,
+		Stats:          in.Stats,
+		Owners:         in.Owners,
+		attempts:       in.Attempts,
 	}
-
-	nextActions := "## Next Actions\n\n- [ ] Re-run the failing test to confirm reproducibility\n- [ ] Check recent changes around the failing test\n- [ ] Consider adding retry/timeout stabilization if flaky\n"
-
-	automation := fmt.Sprintf("## Automation\n\n- Fingerprint: `%s`\n- Labels: %s\n- Last scan: %s\n",
-		in.Fingerprint.Fingerprint,
-		strings.Join(labels, ", "),
-		formatTime(time.Now()),
-	)
-
-	return joinBlocks(
-		wrapBlock("SUMMARY", summary),
-		wrapBlock("EVIDENCE", evidence),
-		wrapBlock("EXCERPTS", excerpts),
-		wrapBlock("NEXT_ACTIONS", nextActions),
-		wrapBlock("AUTOMATION", automation),
-	)
 }
 
 func wrapBlock(name, content string) string {
@@ -185,6 +373,17 @@ func summarizeSignature(sig string) string {
 	return line
 }
 
+func attemptOutcome(occ extract.Occurrence, attempts []store.JobAttempt) string {
+	attempt := occ.RunAttempt
+	if attempt == 0 {
+		attempt = 1
+	}
+	if passed := store.PassedOnRerun(attempts, occ.JobName, attempt); passed != 0 {
+		return fmt.Sprintf("#%d failed → passed on #%d", attempt, passed)
+	}
+	return fmt.Sprintf("#%d", attempt)
+}
+
 func shortSHA(sha string) string {
 	if len(sha) <= 7 {
 		return sha
@@ -1,12 +1,14 @@
 package issue
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/okJiang/flaky-test-cleaner/internal/classify"
 	"github.com/okJiang/flaky-test-cleaner/internal/extract"
+	"github.com/okJiang/flaky-test-cleaner/internal/github"
 	"github.com/okJiang/flaky-test-cleaner/internal/store"
 )
 
@@ -43,3 +45,90 @@ func TestPlanIssueUpdateCreatesBody(t *testing.T) {
 		t.Fatalf("expected summary block")
 	}
 }
+
+func TestReconcileLabelsSwapsOnlyManaged(t *testing.T) {
+	current := []string{"type/ci", LabelAIManaged, LabelNeedsTriage, "component/scheduling", "flaky-test-cleaner/not-flaky"}
+	add, remove := ReconcileLabels(current, []string{LabelAIManaged, LabelFlakyTest})
+	if len(add) != 1 || add[0] != LabelFlakyTest {
+		t.Fatalf("unexpected add %v", add)
+	}
+	if len(remove) != 1 || remove[0] != LabelNeedsTriage {
+		t.Fatalf("unexpected remove %v", remove)
+	}
+	add, remove = ReconcileLabels([]string{LabelAIManaged, LabelFlakyTest}, []string{LabelAIManaged, LabelFlakyTest})
+	if len(add) != 0 || len(remove) != 0 {
+		t.Fatalf("expected no changes, got add=%v remove=%v", add, remove)
+	}
+}
+
+func TestPlanIssueUpdateSkipsUnchangedAndRecentEdits(t *testing.T) {
+	mgr := NewManager(Options{Owner: "tikv", Repo: "pd", MinUpdateInterval: time.Hour})
+	seen := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	in := PlanInput{
+		Fingerprint:    store.FingerprintRecord{Fingerprint: "abc", TestName: "TestFoo", IssueNumber: 12, FirstSeenAt: seen, LastSeenAt: seen},
+		Occurrences:    []extract.Occurrence{{RunID: 1, TestName: "TestFoo", ErrorSignature: "panic: boom", OccurredAt: seen}},
+		Classification: classify.Result{Class: classify.ClassFlakyTest, Confidence: 0.8},
+	}
+	first, err := mgr.PlanIssueUpdate(in)
+	if err != nil || first.Noop || first.ContentHash == "" {
+		t.Fatalf("expected an update with a content hash, got %+v err=%v", first, err)
+	}
+
+	in.Fingerprint.IssueContentHash = first.ContentHash
+	in.Fingerprint.IssueUpdatedAt = time.Now().Add(-2 * time.Hour)
+	again, err := mgr.PlanIssueUpdate(in)
+	if err != nil || !again.Noop || again.Reason != "unchanged" {
+		t.Fatalf("expected unchanged no-op, got %+v err=%v", again, err)
+	}
+
+	in.Classification = classify.Result{Class: classify.ClassLikelyRegression, Confidence: 0.9}
+	changed, err := mgr.PlanIssueUpdate(in)
+	if err != nil || changed.Noop || changed.ContentHash == first.ContentHash {
+		t.Fatalf("expected update after classification change, got %+v err=%v", changed, err)
+	}
+
+	in.Fingerprint.IssueUpdatedAt = time.Now().Add(-10 * time.Minute)
+	deferred, err := mgr.PlanIssueUpdate(in)
+	if err != nil || !deferred.Noop {
+		t.Fatalf("expected edit to wait for the minimum interval, got %+v err=%v", deferred, err)
+	}
+}
+
+func TestMatchHumanIssues(t *testing.T) {
+	items := []github.Issue{
+		{Number: 30, Title: "TestRegionCache is unstable", State: "open"},
+		{Number: 12, Title: "TestRegionCache failed in CI", State: "open"},
+		{Number: 8, Title: "TestRegionCache is flaky", State: "closed"},
+		{Number: 40, Title: "scheduler refactor", Body: "this breaks TestRegionCache", State: "open"},
+		{Number: 41, Title: "[flaky] TestRegionCache — panic", State: "open", Labels: []github.Label{{Name: LabelAIManaged}}},
+		{Number: 42, Title: "TestRegionCacheSplit is unstable", State: "open"},
+	}
+	res := matchHumanIssues("TestRegionCache", items)
+	if res.Strong == nil || res.Strong.Number != 30 {
+		t.Fatalf("expected #30 adopted, got %+v", res.Strong)
+	}
+	var weak []int
+	for _, iss := range res.Weak {
+		weak = append(weak, iss.Number)
+	}
+	if fmt.Sprint(weak) != "[12 8 40]" {
+		t.Fatalf("unexpected weak matches %v", weak)
+	}
+}
+
+func TestMatchHumanIssuesFailureWordingIsWeak(t *testing.T) {
+	items := []github.Issue{
+		{Number: 5, Title: "TestX fails after refactor", State: "open"},
+		{Number: 6, Title: "TestX intermittently times out", State: "open"},
+	}
+	res := matchHumanIssues("TestX", items)
+	if res.Strong == nil || res.Strong.Number != 6 {
+		t.Fatalf("expected #6 adopted, got %+v", res.Strong)
+	}
+	if len(res.Weak) != 1 || res.Weak[0].Number != 5 {
+		t.Fatalf("expected #5 only cross-referenced, got %+v", res.Weak)
+	}
+	if res := matchHumanIssues("TestX", items[:1]); res.Strong != nil {
+		t.Fatalf("failure wording adopted #%d", res.Strong.Number)
+	}
+}
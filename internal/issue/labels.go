@@ -0,0 +1,41 @@
+package issue
+
+const (
+	LabelPrefix      = "flaky-test-cleaner/"
+	LabelAIManaged   = LabelPrefix + "ai-managed"
+	LabelFlakyTest   = LabelPrefix + "flaky-test"
+	LabelNeedsTriage = LabelPrefix + "needs-triage"
+	LabelDigest      = LabelPrefix + "digest"
+)
+
+// ownedLabels are the labels the tool applies from a classification. Other
+// labels under LabelPrefix (for example maintainer verdict labels such as
+// flaky-test-cleaner/not-flaky) are never removed.
+var ownedLabels = map[string]bool{
+	LabelAIManaged:   true,
+	LabelFlakyTest:   true,
+	LabelNeedsTriage: true,
+}
+
+// ReconcileLabels returns the labels to add and remove so that current carries
+// every desired label and no stale tool-owned label; labels outside
+// LabelPrefix are left alone.
+func ReconcileLabels(current, desired []string) (add, remove []string) {
+	have := map[string]bool{}
+	for _, l := range current {
+		have[l] = true
+	}
+	want := map[string]bool{}
+	for _, l := range desired {
+		want[l] = true
+		if !have[l] {
+			add = append(add, l)
+		}
+	}
+	for _, l := range current {
+		if ownedLabels[l] && !want[l] {
+			remove = append(remove, l)
+		}
+	}
+	return add, remove
+}
@@ -0,0 +1,79 @@
+package issue
+
+import (
+	"context"
+	"time"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/extract"
+	"github.com/okJiang/flaky-test-cleaner/internal/github"
+	"github.com/okJiang/flaky-test-cleaner/internal/store"
+)
+
+// IssueState maps a GitHub issue to the state tracked per fingerprint.
+func IssueState(iss github.Issue) (string, time.Time) {
+	if iss.State != "closed" {
+		return store.IssueStateOpen, time.Time{}
+	}
+	var closedAt time.Time
+	if iss.ClosedAt != nil {
+		closedAt = *iss.ClosedAt
+	}
+	if iss.StateReason == "not_planned" {
+		return store.IssueStateNotPlanned, closedAt
+	}
+	return store.IssueStateClosed, closedAt
+}
+
+// CloseQuiet closes an issue as completed after its fingerprint has not
+// failed for quiet.
+func (m *Manager) CloseQuiet(ctx context.Context, gh *github.Client, number int, lastSeen time.Time, quiet time.Duration) error {
+	if number == 0 {
+		return nil
+	}
+	body, err := m.opts.Templates.execute(tmplCloseQuiet, CloseQuietData{QuietDays: int(quiet.Hours() / 24), LastSeen: lastSeen})
+	if err != nil {
+		return err
+	}
+	if m.opts.DryRun {
+		return m.record(Action{Kind: ActionClose, IssueNumber: number, Comments: []ActionComment{{Body: body}}, State: "closed", StateReason: "completed"})
+	}
+	if _, err := gh.CreateComment(ctx, m.opts.Owner, m.opts.Repo, number, body); err != nil {
+		return err
+	}
+	state, reason := "closed", "completed"
+	_, err = gh.UpdateIssue(ctx, m.opts.Owner, m.opts.Repo, number, github.UpdateIssueInput{
+		State:       &state,
+		StateReason: &reason,
+	})
+	return err
+}
+
+// Reopen reopens an issue closed as completed whose fingerprint failed again,
+// linking the runs that failed after it was closed.
+func (m *Manager) Reopen(ctx context.Context, gh *github.Client, number int, closedAt time.Time, occs []extract.Occurrence) error {
+	if number == 0 {
+		return nil
+	}
+	data := ReopenData{ClosedAt: closedAt}
+	seen := map[int64]bool{}
+	for _, occ := range occs {
+		if !occ.OccurredAt.After(closedAt) || seen[occ.RunID] {
+			continue
+		}
+		seen[occ.RunID] = true
+		data.Occurrences = append(data.Occurrences, occ)
+	}
+	body, err := m.opts.Templates.execute(tmplReopen, data)
+	if err != nil {
+		return err
+	}
+	if m.opts.DryRun {
+		return m.record(Action{Kind: ActionReopen, IssueNumber: number, Comments: []ActionComment{{Body: body}}, State: "open"})
+	}
+	if _, err := gh.CreateComment(ctx, m.opts.Owner, m.opts.Repo, number, body); err != nil {
+		return err
+	}
+	state := "open"
+	_, err = gh.UpdateIssue(ctx, m.opts.Owner, m.opts.Repo, number, github.UpdateIssueInput{State: &state})
+	return err
+}
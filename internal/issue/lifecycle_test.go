@@ -0,0 +1,30 @@
+package issue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/github"
+	"github.com/okJiang/flaky-test-cleaner/internal/store"
+)
+
+func TestIssueState(t *testing.T) {
+	closed := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		iss  github.Issue
+		want string
+	}{
+		{github.Issue{State: "open"}, store.IssueStateOpen},
+		{github.Issue{State: "closed", StateReason: "completed", ClosedAt: &closed}, store.IssueStateClosed},
+		{github.Issue{State: "closed", StateReason: "not_planned", ClosedAt: &closed}, store.IssueStateNotPlanned},
+	}
+	for _, c := range cases {
+		got, at := IssueState(c.iss)
+		if got != c.want {
+			t.Fatalf("IssueState(%+v) = %q, want %q", c.iss, got, c.want)
+		}
+		if c.iss.ClosedAt != nil && !at.Equal(closed) {
+			t.Fatalf("expected closed at %s, got %s", closed, at)
+		}
+	}
+}
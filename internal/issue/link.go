@@ -0,0 +1,129 @@
+package issue
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/github"
+)
+
+// maxManagedPages bounds the fallback scan of ai-managed issues.
+const maxManagedPages = 10
+
+var reFingerprintMarker = regexp.MustCompile("Fingerprint: `([0-9a-f]+)`")
+
+// FingerprintFromBody returns the fingerprint recorded in the AUTOMATION block
+// of an issue body, or "" when there is none.
+func FingerprintFromBody(body string) string {
+	blocks, _ := parseBlocks(body)
+	for _, b := range blocks {
+		if b.name != "AUTOMATION" {
+			continue
+		}
+		if m := reFingerprintMarker.FindStringSubmatch(body[b.start:b.end]); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// FindIssue looks on GitHub for an issue already tracking fingerprint, so a
+// lost store row does not lead to a duplicate issue. The search API is tried
+// first; since its index lags, the first miss scans every ai-managed issue
+// once and later lookups use that scan. The oldest matching issue wins.
+// Adopted human-filed issues carry the fingerprint in our comment rather than
+// in their body, and are found through it.
+func (m *Manager) FindIssue(ctx context.Context, gh *github.Client, fingerprint string) (github.Issue, bool, error) {
+	if m.managed != nil {
+		iss, ok := m.managed[fingerprint]
+		return iss, ok, nil
+	}
+	comments := func(number int) ([]github.IssueComment, error) {
+		return gh.ListIssueComments(ctx, m.opts.Owner, m.opts.Repo, number)
+	}
+	query := fmt.Sprintf(`repo:%s/%s is:issue label:"%s" "%s" in:body,comments`, m.opts.Owner, m.opts.Repo, LabelAIManaged, fingerprint)
+	if items, err := gh.SearchIssues(ctx, query, 20); err == nil {
+		iss, ok, err := oldestWithFingerprint(items, fingerprint, comments)
+		if err != nil {
+			return github.Issue{}, false, err
+		}
+		if ok {
+			return iss, true, nil
+		}
+	} else if ctx.Err() != nil {
+		return github.Issue{}, false, ctx.Err()
+	}
+
+	managed := map[string]github.Issue{}
+	for page := 1; page <= maxManagedPages; page++ {
+		items, err := gh.ListIssues(ctx, m.opts.Owner, m.opts.Repo, github.ListIssuesOptions{
+			Labels:  LabelAIManaged,
+			State:   "all",
+			PerPage: 100,
+			Page:    page,
+		})
+		if err != nil {
+			return github.Issue{}, false, err
+		}
+		for _, iss := range items {
+			fps, err := issueFingerprints(iss, comments)
+			if err != nil {
+				return github.Issue{}, false, err
+			}
+			for _, fp := range fps {
+				if prev, ok := managed[fp]; !ok || iss.Number < prev.Number {
+					managed[fp] = iss
+				}
+			}
+		}
+		if len(items) < 100 {
+			break
+		}
+	}
+	m.managed = managed
+	iss, ok := managed[fingerprint]
+	return iss, ok, nil
+}
+
+func oldestWithFingerprint(items []github.Issue, fingerprint string, comments func(number int) ([]github.IssueComment, error)) (github.Issue, bool, error) {
+	var best github.Issue
+	found := false
+	for _, iss := range items {
+		fps, err := issueFingerprints(iss, comments)
+		if err != nil {
+			return github.Issue{}, false, err
+		}
+		if !slices.Contains(fps, fingerprint) {
+			continue
+		}
+		if !found || iss.Number < best.Number {
+			best = iss
+			found = true
+		}
+	}
+	return best, found, nil
+}
+
+// issueFingerprints returns the fingerprints an ai-managed issue tracks: the
+// one in its body, or for an adopted issue, those in our comments on it.
+func issueFingerprints(iss github.Issue, comments func(number int) ([]github.IssueComment, error)) ([]string, error) {
+	if fp := FingerprintFromBody(iss.Body); fp != "" {
+		return []string{fp}, nil
+	}
+	if !hasLabel(iss, LabelAIManaged) {
+		return nil, nil
+	}
+	list, err := comments(iss.Number)
+	if err != nil {
+		return nil, err
+	}
+	var fps []string
+	for _, c := range list {
+		if fp := FingerprintFromBody(c.Body); fp != "" && !slices.Contains(fps, fp) {
+			fps = append(fps, fp)
+		}
+	}
+	return fps, nil
+}
@@ -0,0 +1,158 @@
+package issue
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/extract"
+	"github.com/okJiang/flaky-test-cleaner/internal/store"
+)
+
+// GitHub rejects issue and comment bodies longer than maxBodyLength
+// characters. Rendered bodies are trimmed to bodyBudget, which leaves room for
+// text maintainers add outside the managed blocks.
+const (
+	maxBodyLength = 65536
+	bodyBudget    = maxBodyLength - 4096
+)
+
+// zeroWidthSpace breaks @mentions and #references without changing how the
+// text reads.
+const zeroWidthSpace = "\u200b"
+
+var (
+	reMention   = regexp.MustCompile(`(^|[^\w` + "`" + `])@([A-Za-z0-9])`)
+	reReference = regexp.MustCompile(`(^|[^&` + "`" + `])(#|GH-)(\d)`)
+	reBackticks = regexp.MustCompile("`+")
+	reSpaces    = regexp.MustCompile(`\s+`)
+)
+
+// text makes untrusted text safe to embed in markdown prose: mentions and
+// issue references are neutralized so they neither ping people nor
+// cross-link unrelated issues.
+func text(s string) string {
+	s = reMention.ReplaceAllString(s, "$1@"+zeroWidthSpace+"$2")
+	return reReference.ReplaceAllString(s, "$1$2"+zeroWidthSpace+"$3")
+}
+
+// cell makes untrusted text safe to embed in a table cell.
+func cell(s string) string {
+	s = strings.TrimSpace(reSpaces.ReplaceAllString(s, " "))
+	if s == "" {
+		return "-"
+	}
+	s = strings.ReplaceAll(s, "|", `\|`)
+	s = strings.ReplaceAll(s, "`", "\\`")
+	return text(s)
+}
+
+// code renders s as an inline code span, whose delimiter is longer than any
+// backtick run in s.
+func code(s string) string {
+	s = reSpaces.ReplaceAllString(s, " ")
+	delim := strings.Repeat("`", longestBacktickRun(s)+1)
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		s = " " + s + " "
+	}
+	return delim + s + delim
+}
+
+// fence renders s as a fenced code block whose fence cannot be closed by
+// backticks in s.
+func fence(s string) string {
+	n := longestBacktickRun(s) + 1
+	if n < 4 {
+		n = 4
+	}
+	delim := strings.Repeat("`", n)
+	return delim + "\n" + strings.TrimRight(s, "\n") + "\n" + delim
+}
+
+func longestBacktickRun(s string) int {
+	longest := 0
+	for _, run := range reBackticks.FindAllString(s, -1) {
+		if len(run) > longest {
+			longest = len(run)
+		}
+	}
+	return longest
+}
+
+// excerptOccurrences returns the occurrences with a log excerpt, oldest
+// first.
+func excerptOccurrences(occs []extract.Occurrence) []extract.Occurrence {
+	var out []extract.Occurrence
+	for _, occ := range occs {
+		if occ.Excerpt != "" {
+			out = append(out, occ)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].OccurredAt.Before(out[j].OccurredAt) })
+	return out
+}
+
+// maxCounts bounds how many values counts lists before summarizing the rest.
+const maxCounts = 3
+
+// counts renders value counts such as branches compactly, most frequent
+// first: "master (12), release-8.1 (3), 2 more".
+func counts(list []store.Count) string {
+	var parts []string
+	for i, c := range list {
+		if i == maxCounts {
+			parts = append(parts, fmt.Sprintf("%d more", len(list)-maxCounts))
+			break
+		}
+		name := c.Name
+		if name == "" {
+			name = "unknown"
+		}
+		parts = append(parts, fmt.Sprintf("%s (%d)", name, c.Count))
+	}
+	return cell(strings.Join(parts, ", "))
+}
+
+// failureRate renders the share of failed executions, or "unknown" without
+// execution data.
+func failureRate(execs store.TestExecutionStats) string {
+	if execs.Executions() == 0 {
+		return "unknown"
+	}
+	return fmt.Sprintf("%d/%d (%.1f%%)", execs.Failed, execs.Executions(), 100*execs.FailureRate())
+}
+
+// trend renders weekly counts as a sparkline with its span and peak.
+func trend(weeks []store.WeeklyCount) string {
+	if len(weeks) == 0 {
+		return "-"
+	}
+	peak := 0
+	for _, w := range weeks {
+		peak = max(peak, w.Count)
+	}
+	return fmt.Sprintf("`%s` last %d weeks, peak %d", sparkline(weeks), len(weeks), peak)
+}
+
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline draws weekly counts as bars scaled to the busiest week; weeks
+// without failures are drawn as the lowest bar.
+func sparkline(weeks []store.WeeklyCount) string {
+	peak := 0
+	for _, w := range weeks {
+		if w.Count > peak {
+			peak = w.Count
+		}
+	}
+	var b strings.Builder
+	for _, w := range weeks {
+		i := 0
+		if w.Count > 0 {
+			i = (w.Count*(len(sparkBars)-1) + peak - 1) / peak
+		}
+		b.WriteRune(sparkBars[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,93 @@
+package issue
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/extract"
+	"github.com/okJiang/flaky-test-cleaner/internal/store"
+)
+
+func TestCell(t *testing.T) {
+	cases := map[string]string{
+		"":                        "-",
+		"a | b":                   `a \| b`,
+		"use `go test`":           "use \\`go test\\`",
+		"ping @alice":             "ping @\u200balice",
+		"see #123 and GH-4":       "see #\u200b123 and GH-\u200b4",
+		"tikv/pd#42":              "tikv/pd#\u200b42",
+		"line one\nline two":      "line one line two",
+		"user@example.com":        "user@example.com",
+		"TestFoo/case#1_is_named": "TestFoo/case#\u200b1_is_named",
+	}
+	for in, want := range cases {
+		if got := cell(in); got != want {
+			t.Fatalf("cell(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFence(t *testing.T) {
+	got := fence("output:\n`````\ndone\n")
+	if !strings.HasPrefix(got, "``````\n") || !strings.HasSuffix(got, "\n``````") {
+		t.Fatalf("fence does not outlast the longest backtick run: %q", got)
+	}
+	if got := fence("plain"); got != "````\nplain\n````" {
+		t.Fatalf("unexpected fence %q", got)
+	}
+	if got := code("a`b"); got != "``a`b``" {
+		t.Fatalf("unexpected code span %q", got)
+	}
+}
+
+func TestBodyDropsOldestExcerpts(t *testing.T) {
+	base := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	var occs []extract.Occurrence
+	for i := 0; i < 5; i++ {
+		occs = append(occs, extract.Occurrence{
+			RunID:      int64(100 + i),
+			TestName:   "TestFoo",
+			Excerpt:    strings.Repeat("x", 20000),
+			OccurredAt: base.Add(time.Duration(i) * time.Hour),
+		})
+	}
+	// Newest first, as returned by the TiDB store.
+	for i, j := 0, len(occs)-1; i < j; i, j = i+1, j-1 {
+		occs[i], occs[j] = occs[j], occs[i]
+	}
+	d := IssueData{TestName: "TestFoo", Occurrences: occs, Excerpts: excerptOccurrences(occs)}
+	body, err := DefaultTemplates().Body(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(body) > maxBodyLength {
+		t.Fatalf("body has %d characters, over the limit", len(body))
+	}
+	if !strings.Contains(body, "<summary>Run 102") || strings.Contains(body, "<summary>Run 101") {
+		t.Fatalf("expected the oldest excerpts to be dropped first")
+	}
+	if !strings.Contains(body, "2 older log excerpt(s) omitted") {
+		t.Fatalf("expected an omission note, got:\n%s", body[len(body)-600:])
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	var weeks []store.WeeklyCount
+	for _, n := range []int{0, 1, 2, 4, 8} {
+		weeks = append(weeks, store.WeeklyCount{Count: n})
+	}
+	if got := sparkline(weeks); got != "▁▂▃▅█" {
+		t.Fatalf("unexpected sparkline %q", got)
+	}
+	list := []store.Count{
+		{Name: "master", Count: 5},
+		{Name: "", Count: 3},
+		{Name: "a|b", Count: 2},
+		{Name: "x", Count: 1},
+		{Name: "y", Count: 1},
+	}
+	if got := counts(list); got != `master (5), unknown (3), a\|b (2), 2 more` {
+		t.Fatalf("unexpected counts %q", got)
+	}
+}
@@ -0,0 +1,182 @@
+package issue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/extract"
+	"github.com/okJiang/flaky-test-cleaner/internal/github"
+)
+
+const (
+	// ownerCommits is how many recent commits touching the test are read for
+	// committers, and maxCommitters how many of their authors are suggested.
+	ownerCommits  = 20
+	maxCommitters = 3
+	// maxAssignees bounds auto-assignment.
+	maxAssignees = 2
+)
+
+var reTestFile = regexp.MustCompile(`\b([\w.-]+_test\.go):\d+`)
+
+// Owners are the people likely to own a failing test.
+type Owners struct {
+	// Path is the test file, or its package directory when the file is not
+	// known; empty when neither is.
+	Path       string
+	CodeOwners []string
+	Committers []string
+}
+
+// Assignees returns the users among the owners, CODEOWNERS first; teams and
+// email owners cannot be assigned.
+func (o Owners) Assignees() []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, owner := range append(append([]string{}, o.CodeOwners...), o.Committers...) {
+		login := strings.TrimPrefix(owner, "@")
+		if (!strings.HasPrefix(owner, "@") && strings.Contains(owner, "@")) || strings.Contains(login, "/") || seen[strings.ToLower(login)] {
+			continue
+		}
+		seen[strings.ToLower(login)] = true
+		out = append(out, login)
+	}
+	return out
+}
+
+// SuggestOwners resolves the CODEOWNERS entries of the file occ's test lives
+// in, read at occ's commit, and the authors of the recent commits touching
+// it. Lookups are cached per commit and path for the lifetime of the Manager.
+// Lookup errors are returned as is; callers treat owners as best-effort.
+func (m *Manager) SuggestOwners(ctx context.Context, gh *github.Client, occ extract.Occurrence) (Owners, error) {
+	p := testFilePath(occ, m.opts.Owner, m.opts.Repo)
+	if p == "" {
+		return Owners{}, nil
+	}
+	rules, err := m.codeownersAt(ctx, gh, occ.HeadSHA)
+	if err != nil {
+		return Owners{}, err
+	}
+	committers, err := m.committers(ctx, gh, p, occ.HeadSHA)
+	if err != nil {
+		return Owners{}, err
+	}
+	return Owners{Path: p, CodeOwners: matchCodeowners(rules, p), Committers: committers}, nil
+}
+
+func (m *Manager) codeownersAt(ctx context.Context, gh *github.Client, ref string) ([]codeownersRule, error) {
+	if rules, ok := m.codeowners[ref]; ok {
+		return rules, nil
+	}
+	var rules []codeownersRule
+	for _, p := range codeownersPaths {
+		data, err := gh.GetFileContents(ctx, m.opts.Owner, m.opts.Repo, p, ref)
+		if errors.Is(err, github.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		rules = parseCodeowners(string(data))
+		break
+	}
+	if m.codeowners == nil {
+		m.codeowners = map[string][]codeownersRule{}
+	}
+	m.codeowners[ref] = rules
+	return rules, nil
+}
+
+func (m *Manager) committers(ctx context.Context, gh *github.Client, p, ref string) ([]string, error) {
+	key := ref + ":" + p
+	if cached, ok := m.authors[key]; ok {
+		return cached, nil
+	}
+	commits, err := gh.ListCommits(ctx, m.opts.Owner, m.opts.Repo, github.ListCommitsOptions{SHA: ref, Path: p, PerPage: ownerCommits})
+	if err != nil && !errors.Is(err, github.ErrNotFound) {
+		return nil, err
+	}
+	var out []string
+	seen := map[string]bool{}
+	for _, c := range commits {
+		if c.Author == nil || c.Author.Type == "Bot" || strings.HasSuffix(c.Author.Login, "[bot]") || seen[c.Author.Login] {
+			continue
+		}
+		seen[c.Author.Login] = true
+		out = append(out, "@"+c.Author.Login)
+		if len(out) == maxCommitters {
+			break
+		}
+	}
+	if m.authors == nil {
+		m.authors = map[string][]string{}
+	}
+	m.authors[key] = out
+	return out, nil
+}
+
+// testFilePath locates occ's test in the repository: the *_test.go file named
+// in the failure output inside the test's package directory, or the directory
+// alone.
+func testFilePath(occ extract.Occurrence, owner, repo string) string {
+	rel := relPackage(occ.Package, owner, repo)
+	if occ.Package == "" || !strings.HasPrefix(rel, ".") {
+		return ""
+	}
+	dir := strings.TrimPrefix(strings.TrimPrefix(rel, "."), "/")
+	for _, text := range []string{occ.ErrorSignature, occ.Excerpt} {
+		if m := reTestFile.FindStringSubmatch(text); m != nil {
+			return path.Join(dir, m[1])
+		}
+	}
+	if dir == "" {
+		return ""
+	}
+	return dir
+}
+
+// AssignError reports that an issue was written but could not be assigned
+// to its owners. Apply returns it together with the issue number, so the
+// caller can still link the issue.
+type AssignError struct {
+	Issue int
+	Err   error
+}
+
+func (e *AssignError) Error() string {
+	return fmt.Sprintf("assign owners to #%d: %v", e.Issue, e.Err)
+}
+
+func (e *AssignError) Unwrap() error { return e.Err }
+
+// assign assigns up to maxAssignees repository collaborators among owners to
+// an issue nobody is assigned to yet. Failures are returned as *AssignError.
+func (m *Manager) assign(ctx context.Context, gh *github.Client, number int, current []github.User, owners []string) error {
+	if !m.opts.AutoAssign || len(current) > 0 || len(owners) == 0 {
+		return nil
+	}
+	var logins []string
+	for _, login := range owners {
+		ok, err := gh.IsCollaborator(ctx, m.opts.Owner, m.opts.Repo, login)
+		if err != nil {
+			return &AssignError{Issue: number, Err: err}
+		}
+		if ok {
+			logins = append(logins, login)
+		}
+		if len(logins) == maxAssignees {
+			break
+		}
+	}
+	if len(logins) == 0 {
+		return nil
+	}
+	if err := gh.AddAssignees(ctx, m.opts.Owner, m.opts.Repo, number, logins); err != nil {
+		return &AssignError{Issue: number, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package issue
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/extract"
+)
+
+func TestTestFilePath(t *testing.T) {
+	cases := []struct {
+		occ  extract.Occurrence
+		want string
+	}{
+		{extract.Occurrence{Package: "github.com/tikv/pd/pkg/foo", Excerpt: "    foo_test.go:12: expected true"}, "pkg/foo/foo_test.go"},
+		{extract.Occurrence{Package: "github.com/tikv/pd/pkg/foo"}, "pkg/foo"},
+		{extract.Occurrence{Package: "github.com/tikv/pd", ErrorSignature: "main_test.go:3: boom"}, "main_test.go"},
+		{extract.Occurrence{Package: "github.com/other/repo/pkg", Excerpt: "x_test.go:1"}, ""},
+		{extract.Occurrence{Excerpt: "x_test.go:1"}, ""},
+	}
+	for _, c := range cases {
+		if got := testFilePath(c.occ, "tikv", "pd"); got != c.want {
+			t.Fatalf("testFilePath(%+v) = %q, want %q", c.occ, got, c.want)
+		}
+	}
+}
+
+func TestOwnersAssignees(t *testing.T) {
+	o := Owners{
+		CodeOwners: []string{"@tikv/pd-maintainers", "@Alice", "ops@example.com"},
+		Committers: []string{"@alice", "@bob"},
+	}
+	if got, want := o.Assignees(), []string{"Alice", "bob"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Assignees() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,124 @@
+package issue
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/classify"
+	"github.com/okJiang/flaky-test-cleaner/internal/store"
+)
+
+// What the policy does with a classified fingerprint.
+const (
+	// DecisionIgnore leaves the fingerprint out of issue handling entirely.
+	DecisionIgnore = "ignore"
+	// DecisionRecord keeps the occurrences in the store without opening an
+	// issue; an issue that already exists is still kept up to date.
+	DecisionRecord = "record"
+	DecisionTriage = "needs-triage"
+	DecisionFlaky  = "flaky"
+)
+
+// DefaultConfidenceThreshold is the confidence a flaky-test classification
+// needs for an issue under the default policy.
+const DefaultConfidenceThreshold = 0.75
+
+// PolicyRule matches classifications and evidence; zero fields match
+// anything.
+type PolicyRule struct {
+	Class           string  `json:"class"`
+	MinConfidence   float64 `json:"min_confidence"`
+	MinOccurrences  int     `json:"min_occurrences"`
+	MinDistinctSHAs int     `json:"min_distinct_shas"`
+	Action          string  `json:"action"`
+}
+
+// Policy maps a fingerprint to a decision by its first matching rule; when
+// none matches, the fingerprint is only recorded.
+type Policy struct {
+	Rules []PolicyRule `json:"rules"`
+}
+
+// DefaultPolicy ignores infra failures, only records likely regressions, and
+// opens flaky-test issues at confidence threshold or above. Anything else is
+// recorded, or gets a needs-triage issue with triage set.
+func DefaultPolicy(threshold float64, triage bool) Policy {
+	fallback := DecisionRecord
+	if triage {
+		fallback = DecisionTriage
+	}
+	return Policy{Rules: []PolicyRule{
+		{Class: string(classify.ClassInfraFlake), Action: DecisionIgnore},
+		{Class: string(classify.ClassLikelyRegression), Action: DecisionRecord},
+		{Class: string(classify.ClassFlakyTest), MinConfidence: threshold, Action: DecisionFlaky},
+		{Action: fallback},
+	}}
+}
+
+// Decision is the outcome of a Policy, with the rule that produced it.
+type Decision struct {
+	Action string
+	// Rule is the 1-based index of the matching rule, 0 if none matched.
+	Rule int
+	// Reason describes the matching rule.
+	Reason string
+}
+
+// OpensIssue reports whether the decision warrants a new issue.
+func (d Decision) OpensIssue() bool {
+	return d.Action == DecisionFlaky || d.Action == DecisionTriage
+}
+
+func (p Policy) Decide(res classify.Result, stats store.OccurrenceStats) Decision {
+	for i, r := range p.Rules {
+		if r.Class != "" && r.Class != string(res.Class) {
+			continue
+		}
+		if res.Confidence < r.MinConfidence || stats.Total < r.MinOccurrences || stats.DistinctSHAs < r.MinDistinctSHAs {
+			continue
+		}
+		return Decision{Action: r.Action, Rule: i + 1, Reason: r.String()}
+	}
+	return Decision{Action: DecisionRecord, Reason: "no rule matched"}
+}
+
+func (r PolicyRule) String() string {
+	var conds []string
+	if r.Class != "" {
+		conds = append(conds, "class "+r.Class)
+	}
+	if r.MinConfidence > 0 {
+		conds = append(conds, fmt.Sprintf("confidence ≥ %.2f", r.MinConfidence))
+	}
+	if r.MinOccurrences > 0 {
+		conds = append(conds, fmt.Sprintf("≥ %d occurrences", r.MinOccurrences))
+	}
+	if r.MinDistinctSHAs > 0 {
+		conds = append(conds, fmt.Sprintf("≥ %d commits", r.MinDistinctSHAs))
+	}
+	if len(conds) == 0 {
+		return "any other result"
+	}
+	return strings.Join(conds, ", ")
+}
+
+func (p Policy) validate() error {
+	for i, r := range p.Rules {
+		switch classify.Class(r.Class) {
+		case "", classify.ClassFlakyTest, classify.ClassInfraFlake, classify.ClassLikelyRegression, classify.ClassUnknown:
+		default:
+			return fmt.Errorf("rule %d: unknown class %q", i+1, r.Class)
+		}
+		switch r.Action {
+		case DecisionIgnore, DecisionRecord, DecisionTriage, DecisionFlaky:
+		default:
+			return fmt.Errorf("rule %d: unknown action %q", i+1, r.Action)
+		}
+	}
+	return nil
+}
+
+// Decide applies the Manager's policy.
+func (m *Manager) Decide(res classify.Result, stats store.OccurrenceStats) Decision {
+	return m.opts.Policy.Decide(res, stats)
+}
@@ -0,0 +1,115 @@
+package issue
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/classify"
+	"github.com/okJiang/flaky-test-cleaner/internal/extract"
+	"github.com/okJiang/flaky-test-cleaner/internal/github"
+	"github.com/okJiang/flaky-test-cleaner/internal/store"
+)
+
+func TestDefaultPolicy(t *testing.T) {
+	cases := []struct {
+		res    classify.Result
+		triage bool
+		want   string
+	}{
+		{classify.Result{Class: classify.ClassInfraFlake, Confidence: 0.9}, false, DecisionIgnore},
+		{classify.Result{Class: classify.ClassLikelyRegression, Confidence: 0.9}, true, DecisionRecord},
+		{classify.Result{Class: classify.ClassFlakyTest, Confidence: 0.8}, false, DecisionFlaky},
+		{classify.Result{Class: classify.ClassFlakyTest, Confidence: 0.6}, false, DecisionRecord},
+		{classify.Result{Class: classify.ClassFlakyTest, Confidence: 0.6}, true, DecisionTriage},
+		{classify.Result{Class: classify.ClassUnknown, Confidence: 0.5}, false, DecisionRecord},
+		{classify.Result{Class: classify.ClassUnknown, Confidence: 0.5}, true, DecisionTriage},
+	}
+	for _, c := range cases {
+		got := DefaultPolicy(0.75, c.triage).Decide(c.res, store.OccurrenceStats{})
+		if got.Action != c.want {
+			t.Fatalf("Decide(%s %.2f, triage=%v) = %+v, want %s", c.res.Class, c.res.Confidence, c.triage, got, c.want)
+		}
+	}
+}
+
+func TestPolicyEvidence(t *testing.T) {
+	p, err := ParseRepoProfile([]byte(`{"policy": {"rules": [
+		{"class": "unknown", "min_occurrences": 5, "min_distinct_shas": 3, "action": "needs-triage"},
+		{"class": "unknown", "action": "ignore"}
+	]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := classify.Result{Class: classify.ClassUnknown, Confidence: 0.5}
+	if got := p.Policy.Decide(res, store.OccurrenceStats{Total: 5, DistinctSHAs: 2}); got.Action != DecisionIgnore || got.Rule != 2 {
+		t.Fatalf("expected rule 2 to ignore, got %+v", got)
+	}
+	got := p.Policy.Decide(res, store.OccurrenceStats{Total: 5, DistinctSHAs: 3})
+	if got.Action != DecisionTriage || got.Reason != "class unknown, ≥ 5 occurrences, ≥ 3 commits" {
+		t.Fatalf("expected rule 1 to open a triage issue, got %+v", got)
+	}
+	if got := p.Policy.Decide(classify.Result{Class: classify.ClassFlakyTest, Confidence: 1}, store.OccurrenceStats{}); got.Action != DecisionRecord || got.Rule != 0 {
+		t.Fatalf("expected unmatched results to be recorded, got %+v", got)
+	}
+
+	if _, err := ParseRepoProfile([]byte(`{"policy": {"rules": [{"action": "close"}]}}`)); err == nil {
+		t.Fatalf("expected unknown action to be rejected")
+	}
+}
+
+func TestPlanIssueUpdateFollowsPolicy(t *testing.T) {
+	seen := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	in := PlanInput{
+		Fingerprint:    store.FingerprintRecord{Fingerprint: "abc", TestName: "TestFoo", FirstSeenAt: seen, LastSeenAt: seen},
+		Occurrences:    []extract.Occurrence{{RunID: 1, TestName: "TestFoo", ErrorSignature: "panic: boom", OccurredAt: seen}},
+		Classification: classify.Result{Class: classify.ClassUnknown, Confidence: 0.5},
+	}
+	change, err := NewManager(Options{Owner: "tikv", Repo: "pd"}).PlanIssueUpdate(in)
+	if err != nil || !change.Noop || !strings.HasPrefix(change.Reason, "policy record") {
+		t.Fatalf("expected unknown result to be recorded only, got %+v err=%v", change, err)
+	}
+
+	mgr := NewManager(Options{Owner: "tikv", Repo: "pd", Policy: DefaultPolicy(0.75, true)})
+	change, err = mgr.PlanIssueUpdate(in)
+	if err != nil || !change.Create {
+		t.Fatalf("expected a needs-triage issue, got %+v err=%v", change, err)
+	}
+	if len(change.Labels) != 2 || change.Labels[1] != LabelNeedsTriage {
+		t.Fatalf("unexpected labels %v", change.Labels)
+	}
+	if !strings.Contains(change.Body, "- Policy: **needs-triage** (rule 4: any other result)") {
+		t.Fatalf("expected the policy in the summary:\n%s", change.Body)
+	}
+}
+
+func TestRecordDecisionDoesNotAdopt(t *testing.T) {
+	seen := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	strong := github.Issue{Number: 12, Title: "TestFoo is flaky", State: "open"}
+	matches := matchHumanIssues("TestFoo", []github.Issue{strong})
+	if matches.Strong == nil {
+		t.Fatalf("expected a strong match")
+	}
+	mgr := NewManager(Options{Owner: "tikv", Repo: "pd"})
+	res := classify.Result{Class: classify.ClassUnknown, Confidence: 0.5}
+	decision := mgr.Decide(res, store.OccurrenceStats{})
+	if iss := matches.Adoptable(decision); iss != nil {
+		t.Fatalf("expected no adoption under a %s decision, got #%d", decision.Action, iss.Number)
+	}
+	// Left unlinked, the fingerprint plans no change, so nothing is posted
+	// on the human-filed issue.
+	change, err := mgr.PlanIssueUpdate(PlanInput{
+		Fingerprint:    store.FingerprintRecord{Fingerprint: "abc", TestName: "TestFoo", FirstSeenAt: seen, LastSeenAt: seen},
+		Occurrences:    []extract.Occurrence{{RunID: 1, TestName: "TestFoo", ErrorSignature: "panic: boom", OccurredAt: seen}},
+		Classification: res,
+		Related:        []github.Issue{strong},
+	})
+	if err != nil || !change.Noop {
+		t.Fatalf("expected no change, got %+v err=%v", change, err)
+	}
+
+	decision = mgr.Decide(classify.Result{Class: classify.ClassFlakyTest, Confidence: 0.9}, store.OccurrenceStats{})
+	if iss := matches.Adoptable(decision); iss == nil || iss.Number != 12 {
+		t.Fatalf("expected #12 adopted under a %s decision, got %+v", decision.Action, iss)
+	}
+}
@@ -0,0 +1,170 @@
+package issue
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/extract"
+	"github.com/okJiang/flaky-test-cleaner/internal/github"
+)
+
+// Recurrence comments carry one hidden marker per note so that rescanning
+// the same evidence never posts it twice.
+const recurrenceMarkerPrefix = "<!-- FTC:RECURRENCE "
+
+const (
+	spikeWindow      = 7 * 24 * time.Hour
+	spikeBaseline    = 4 // weeks before the spike window used as the baseline
+	spikeMinimumHits = 3
+)
+
+type Recurrence struct {
+	Key  string
+	Note string
+}
+
+// Marker is the hidden comment that identifies this note.
+func (r Recurrence) Marker() string {
+	return recurrenceMarkerPrefix + r.Key + " -->"
+}
+
+// DetectRecurrence compares occ against the earlier occurrences of its
+// fingerprint and reports what is newsworthy: a failure after a quiet period,
+// a platform or branch not affected before, or a weekly count spiking over the
+// previous weeks' average.
+func (m *Manager) DetectRecurrence(occ extract.Occurrence, history []extract.Occurrence) []Recurrence {
+	var prior []extract.Occurrence
+	for _, h := range history {
+		if h.OccurredAt.Before(occ.OccurredAt) {
+			prior = append(prior, h)
+		}
+	}
+	if len(prior) == 0 {
+		return nil
+	}
+	run := fmt.Sprintf("[run %d](%s)", occ.RunID, occ.RunURL)
+
+	var out []Recurrence
+	latest := prior[0].OccurredAt
+	platforms := map[string]bool{}
+	branches := map[string]bool{}
+	for _, p := range prior {
+		if p.OccurredAt.After(latest) {
+			latest = p.OccurredAt
+		}
+		platforms[p.RunnerOS] = true
+		branches[p.Branch] = true
+	}
+	if m.opts.QuietPeriod > 0 {
+		if gap := occ.OccurredAt.Sub(latest); gap >= m.opts.QuietPeriod {
+			out = append(out, Recurrence{
+				Key:  fmt.Sprintf("quiet run=%d", occ.RunID),
+				Note: fmt.Sprintf("Failed again after %d quiet days: %s.", int(gap.Hours()/24), run),
+			})
+		}
+	}
+	if occ.RunnerOS != "" && !platforms[occ.RunnerOS] {
+		out = append(out, Recurrence{
+			Key:  "platform=" + occ.RunnerOS,
+			Note: fmt.Sprintf("First failure on platform %s: %s.", code(occ.RunnerOS), run),
+		})
+	}
+	if occ.Branch != "" && !branches[occ.Branch] {
+		out = append(out, Recurrence{
+			Key:  "branch=" + occ.Branch,
+			Note: fmt.Sprintf("First failure on branch %s: %s.", code(occ.Branch), run),
+		})
+	}
+	if m.opts.SpikeFactor > 0 {
+		recent, before := 1, 0
+		for _, p := range prior {
+			age := occ.OccurredAt.Sub(p.OccurredAt)
+			switch {
+			case age < spikeWindow:
+				recent++
+			case age < spikeWindow*(spikeBaseline+1):
+				before++
+			}
+		}
+		avg := float64(before) / spikeBaseline
+		if before > 0 && recent >= spikeMinimumHits && float64(recent) >= m.opts.SpikeFactor*avg {
+			year, week := occ.OccurredAt.ISOWeek()
+			out = append(out, Recurrence{
+				Key:  fmt.Sprintf("spike week=%d-W%02d", year, week),
+				Note: fmt.Sprintf("Failure frequency spiked: %d failures in the last 7 days vs %.1f per week before (latest %s).", recent, avg, run),
+			})
+		}
+	}
+	return out
+}
+
+// PostRecurrence comments on the issue with every note not posted before. A
+// recurrence comment newer than CommentInterval is edited instead of adding
+// another one, so watchers get at most one new comment per interval.
+func (m *Manager) PostRecurrence(ctx context.Context, gh *github.Client, number int, recs []Recurrence) error {
+	if number == 0 || len(recs) == 0 || (m.opts.DryRun && m.opts.Recorder == nil) {
+		return nil
+	}
+	comments, err := gh.ListIssueComments(ctx, m.opts.Owner, m.opts.Repo, number)
+	if err != nil {
+		return err
+	}
+	var fresh []Recurrence
+	for _, r := range recs {
+		if !commentsContain(comments, r.Marker()) {
+			fresh = append(fresh, r)
+		}
+	}
+	if len(fresh) == 0 {
+		return nil
+	}
+
+	data := RecurrenceData{Notes: fresh}
+	if last, ok := lastRecurrenceComment(comments); ok && time.Since(last.CreatedAt) < m.opts.CommentInterval {
+		notes, err := m.opts.Templates.execute(tmplRecurrenceNotes, data)
+		if err != nil {
+			return err
+		}
+		body := strings.TrimRight(last.Body, "\n") + "\n" + notes
+		if m.opts.DryRun {
+			return m.record(Action{Kind: ActionRecur, IssueNumber: number, Comments: []ActionComment{{CommentID: last.ID, Body: body}}})
+		}
+		_, err = gh.UpdateComment(ctx, m.opts.Owner, m.opts.Repo, last.ID, body)
+		return err
+	}
+	body, err := m.opts.Templates.execute(tmplRecurrence, data)
+	if err != nil {
+		return err
+	}
+	if m.opts.DryRun {
+		return m.record(Action{Kind: ActionRecur, IssueNumber: number, Comments: []ActionComment{{Body: body}}})
+	}
+	_, err = gh.CreateComment(ctx, m.opts.Owner, m.opts.Repo, number, body)
+	return err
+}
+
+func commentsContain(comments []github.IssueComment, marker string) bool {
+	for _, c := range comments {
+		if strings.Contains(c.Body, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func lastRecurrenceComment(comments []github.IssueComment) (github.IssueComment, bool) {
+	var last github.IssueComment
+	found := false
+	for _, c := range comments {
+		if !strings.Contains(c.Body, recurrenceMarkerPrefix) {
+			continue
+		}
+		if !found || c.CreatedAt.After(last.CreatedAt) {
+			last = c
+			found = true
+		}
+	}
+	return last, found
+}
@@ -0,0 +1,48 @@
+package issue
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/extract"
+)
+
+func TestDetectRecurrence(t *testing.T) {
+	mgr := NewManager(Options{QuietPeriod: 7 * 24 * time.Hour, SpikeFactor: 3})
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	occ := func(runID int64, ago time.Duration, os, branch string) extract.Occurrence {
+		return extract.Occurrence{RunID: runID, RunnerOS: os, Branch: branch, OccurredAt: now.Add(-ago)}
+	}
+
+	history := []extract.Occurrence{occ(1, 20*day, "ubuntu-latest", "master"), occ(2, 15*day, "ubuntu-latest", "master")}
+	recs := mgr.DetectRecurrence(occ(3, 0, "macos-latest", "release-8.1"), history)
+	keys := recurrenceKeys(recs)
+	for _, want := range []string{"quiet run=3", "platform=macos-latest", "branch=release-8.1"} {
+		if !strings.Contains(keys, want) {
+			t.Fatalf("expected %q in %s", want, keys)
+		}
+	}
+
+	if recs := mgr.DetectRecurrence(occ(3, 0, "ubuntu-latest", "master"), append(history, occ(4, day, "ubuntu-latest", "master"))); len(recs) != 0 {
+		t.Fatalf("expected nothing for a routine failure, got %s", recurrenceKeys(recs))
+	}
+
+	spiky := []extract.Occurrence{occ(1, 20*day, "ubuntu-latest", "master"), occ(2, day, "ubuntu-latest", "master"), occ(3, 2*day, "ubuntu-latest", "master"), occ(4, 3*day, "ubuntu-latest", "master")}
+	if keys := recurrenceKeys(mgr.DetectRecurrence(occ(5, 0, "ubuntu-latest", "master"), spiky)); !strings.Contains(keys, "spike week=") {
+		t.Fatalf("expected a spike, got %s", keys)
+	}
+
+	if recs := mgr.DetectRecurrence(occ(1, 0, "macos-latest", "master"), nil); len(recs) != 0 {
+		t.Fatalf("expected no recurrence without history, got %s", recurrenceKeys(recs))
+	}
+}
+
+func recurrenceKeys(recs []Recurrence) string {
+	var keys []string
+	for _, r := range recs {
+		keys = append(keys, r.Key)
+	}
+	return strings.Join(keys, ", ")
+}
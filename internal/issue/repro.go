@@ -0,0 +1,180 @@
+package issue
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/extract"
+)
+
+// RepoProfile holds per-repository settings read from a JSON file.
+type RepoProfile struct {
+	Repro ReproProfile `json:"repro"`
+	// Policy replaces the default policy when it has rules.
+	Policy Policy `json:"policy"`
+}
+
+// ReproProfile configures the reproduction command in Next Actions. Command
+// is a text/template executed with a Repro; quote shell-quotes a value.
+type ReproProfile struct {
+	Command          string `json:"command"`
+	Count            int    `json:"count"`
+	Timeout          string `json:"timeout"`
+	FailpointEnable  string `json:"failpoint_enable"`
+	FailpointDisable string `json:"failpoint_disable"`
+
+	tmpl *template.Template
+}
+
+const defaultReproCommand = `go test {{.Package}} -run {{quote .Run}} -count={{.Count}}{{if .Race}} -race{{end}} -timeout {{.Timeout}}`
+
+// DefaultRepoProfile returns the profile used when no file is configured.
+func DefaultRepoProfile() RepoProfile {
+	p, err := ParseRepoProfile([]byte("{}"))
+	if err != nil {
+		panic(fmt.Sprintf("invalid default repo profile: %v", err))
+	}
+	return p
+}
+
+// LoadRepoProfile reads a profile file; fields it leaves empty keep their
+// defaults. An empty path returns the default profile.
+func LoadRepoProfile(path string) (RepoProfile, error) {
+	if path == "" {
+		return DefaultRepoProfile(), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return RepoProfile{}, err
+	}
+	p, err := ParseRepoProfile(data)
+	if err != nil {
+		return RepoProfile{}, fmt.Errorf("repo profile %s: %w", path, err)
+	}
+	return p, nil
+}
+
+func ParseRepoProfile(data []byte) (RepoProfile, error) {
+	p := RepoProfile{Repro: ReproProfile{
+		Command:          defaultReproCommand,
+		Count:            50,
+		Timeout:          "20m",
+		FailpointEnable:  "make failpoint-enable",
+		FailpointDisable: "make failpoint-disable",
+	}}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return RepoProfile{}, err
+	}
+	tmpl, err := template.New("repro").Funcs(template.FuncMap{"quote": shellQuote}).Option("missingkey=error").Parse(p.Repro.Command)
+	if err != nil {
+		return RepoProfile{}, fmt.Errorf("repro command: %w", err)
+	}
+	p.Repro.tmpl = tmpl
+	if err := p.Policy.validate(); err != nil {
+		return RepoProfile{}, fmt.Errorf("policy: %w", err)
+	}
+	if _, err := p.Repro.command(Repro{Package: "./pkg/sample", Run: "^TestSample$", Count: 1, Timeout: "1m"}); err != nil {
+		return RepoProfile{}, fmt.Errorf("repro command: %w", err)
+	}
+	return p, nil
+}
+
+// Repro is what the Next Actions template sees about reproducing a failure.
+type Repro struct {
+	// Package is relative to the repository root when it lies inside it,
+	// "./..." when unknown.
+	Package   string
+	Run       string
+	Count     int
+	Timeout   string
+	Race      bool
+	GoVersion string
+	// Failpoint is set when the job ran with failpoints; FailpointEnable and
+	// FailpointDisable are then the commands to run around Command.
+	Failpoint        bool
+	FailpointEnable  string
+	FailpointDisable string
+	Command          string
+}
+
+func (p ReproProfile) command(r Repro) (string, error) {
+	var buf bytes.Buffer
+	if err := p.tmpl.Execute(&buf, r); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// repro builds the reproduction command for testName from its most recent
+// occurrence with a known package.
+func (m *Manager) repro(testName string, occs []extract.Occurrence) (*Repro, error) {
+	if testName == "" {
+		return nil, nil
+	}
+	p := m.opts.Profile.Repro
+	r := Repro{
+		Run:     runPattern(testName),
+		Count:   p.Count,
+		Timeout: p.Timeout,
+	}
+	var latest extract.Occurrence
+	for _, occ := range occs {
+		r.Race = r.Race || occ.Race
+		r.Failpoint = r.Failpoint || occ.Failpoint
+		if occ.Package != "" && (latest.Package == "" || occ.OccurredAt.After(latest.OccurredAt)) {
+			latest = occ
+		}
+		if r.GoVersion == "" {
+			r.GoVersion = occ.GoVersion
+		}
+	}
+	if latest.GoVersion != "" {
+		r.GoVersion = latest.GoVersion
+	}
+	r.Package = relPackage(latest.Package, m.opts.Owner, m.opts.Repo)
+	if r.Failpoint {
+		r.FailpointEnable = p.FailpointEnable
+		r.FailpointDisable = p.FailpointDisable
+	}
+	cmd, err := p.command(r)
+	if err != nil {
+		return nil, err
+	}
+	r.Command = cmd
+	return &r, nil
+}
+
+// runPattern anchors every level of a subtest name for go test -run.
+func runPattern(testName string) string {
+	parts := strings.Split(testName, "/")
+	for i, p := range parts {
+		parts[i] = "^" + regexp.QuoteMeta(p) + "$"
+	}
+	return strings.Join(parts, "/")
+}
+
+// relPackage turns an import path inside github.com/owner/repo into a path
+// relative to the repository root.
+func relPackage(pkg, owner, repo string) string {
+	if pkg == "" {
+		return "./..."
+	}
+	root := strings.ToLower("github.com/" + owner + "/" + repo)
+	lower := strings.ToLower(pkg)
+	switch {
+	case lower == root:
+		return "."
+	case strings.HasPrefix(lower, root+"/"):
+		return "." + pkg[len(root):]
+	}
+	return pkg
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,60 @@
+package issue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/extract"
+)
+
+func TestRepro(t *testing.T) {
+	m := NewManager(Options{Owner: "tikv", Repo: "pd"})
+	at := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	r, err := m.repro("TestSuite/TestCase", []extract.Occurrence{
+		{Package: "github.com/tikv/pd/pkg/old", GoVersion: "go1.21.0", OccurredAt: at},
+		{Package: "github.com/tikv/pd/pkg/foo", GoVersion: "go1.22.1", Race: true, OccurredAt: at.Add(time.Hour)},
+		{OccurredAt: at.Add(2 * time.Hour)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "go test ./pkg/foo -run '^TestSuite$/^TestCase$' -count=50 -race -timeout 20m"
+	if r.Command != want {
+		t.Fatalf("unexpected command\n got: %s\nwant: %s", r.Command, want)
+	}
+	if r.GoVersion != "go1.22.1" || r.FailpointEnable != "" {
+		t.Fatalf("unexpected repro %+v", r)
+	}
+
+	r, err = m.repro("TestFoo", []extract.Occurrence{{Failpoint: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Package != "./..." || r.FailpointEnable != "make failpoint-enable" {
+		t.Fatalf("unexpected repro %+v", r)
+	}
+}
+
+func TestParseRepoProfile(t *testing.T) {
+	p, err := ParseRepoProfile([]byte(`{"repro": {"command": "make test PKG={{.Package}} RUN={{quote .Run}}", "count": 10}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager(Options{Owner: "tikv", Repo: "pd", Profile: p})
+	r, err := m.repro("TestIt's", []extract.Occurrence{{Package: "github.com/tikv/pd"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `make test PKG=. RUN='^TestIt'\''s$'`; r.Command != want {
+		t.Fatalf("unexpected command\n got: %s\nwant: %s", r.Command, want)
+	}
+	if r.Count != 10 || r.Timeout != "20m" {
+		t.Fatalf("expected unset fields to keep their defaults, got %+v", r)
+	}
+
+	for _, bad := range []string{`{"repro": {"command": "{{.Nope}}"}}`, `{"repro": {"command": "{{if}}"}}`, `{`} {
+		if _, err := ParseRepoProfile([]byte(bad)); err == nil {
+			t.Fatalf("expected %s to be rejected", bad)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package issue
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/classify"
+	"github.com/okJiang/flaky-test-cleaner/internal/extract"
+	"github.com/okJiang/flaky-test-cleaner/internal/github"
+	"github.com/okJiang/flaky-test-cleaner/internal/store"
+)
+
+//go:embed templates/*.tmpl
+var defaultTemplates embed.FS
+
+// Template names; an override directory may replace any of them with a file
+// of the same name.
+const (
+	tmplTitle           = "title.tmpl"
+	tmplSummary         = "summary.tmpl"
+	tmplEvidence        = "evidence.tmpl"
+	tmplExcerpts        = "excerpts.tmpl"
+	tmplNextActions     = "next_actions.tmpl"
+	tmplOwners          = "owners.tmpl"
+	tmplAutomation      = "automation.tmpl"
+	tmplRecurrence      = "recurrence.tmpl"
+	tmplRecurrenceNotes = "recurrence_notes.tmpl"
+	tmplReopen          = "reopen.tmpl"
+	tmplCloseQuiet      = "close_quiet.tmpl"
+	tmplMerged          = "merged.tmpl"
+	tmplDigest          = "digest.tmpl"
+)
+
+// bodyBlocks lists the managed blocks of an issue body in order.
+var bodyBlocks = []struct {
+	name string
+	tmpl string
+}{
+	{"SUMMARY", tmplSummary},
+	{"EVIDENCE", tmplEvidence},
+	{"EXCERPTS", tmplExcerpts},
+	{"NEXT_ACTIONS", tmplNextActions},
+	{"OWNERS", tmplOwners},
+	{"AUTOMATION", tmplAutomation},
+}
+
+// IssueData is what the title and body templates see.
+type IssueData struct {
+	Fingerprint    store.FingerprintRecord
+	TestName       string
+	ShortSignature string
+	// Occurrences are the most recent occurrences.
+	Occurrences []extract.Occurrence
+	// Excerpts are the occurrences whose log excerpt is shown, oldest first.
+	// The oldest are dropped, and counted in OmittedExcerpts, when the body
+	// would not fit GitHub's size limit.
+	Excerpts        []extract.Occurrence
+	OmittedExcerpts int
+	Classification  classify.Result
+	// Decision is the policy decision behind the issue.
+	Decision   Decision
+	Executions store.TestExecutionStats
+	Priority   string
+	FirstSeen  time.Time
+	LastSeen   time.Time
+	Labels     []string
+	Related    []github.Issue
+	Stats      store.OccurrenceStats
+	Owners     Owners
+	// Repro is nil when the test name is unknown.
+	Repro *Repro
+	// UpdatedAt is zero while computing the content hash.
+	UpdatedAt time.Time
+
+	attempts map[int64][]store.JobAttempt
+}
+
+// Attempt describes the attempt occ failed in, and the attempt that passed on
+// re-run if any.
+func (d IssueData) Attempt(occ extract.Occurrence) string {
+	return attemptOutcome(occ, d.attempts[occ.RunID])
+}
+
+type RecurrenceData struct {
+	Notes []Recurrence
+}
+
+type ReopenData struct {
+	ClosedAt    time.Time
+	Occurrences []extract.Occurrence
+}
+
+type CloseQuietData struct {
+	QuietDays int
+	LastSeen  time.Time
+}
+
+type MergedData struct {
+	Into        int
+	Fingerprint string
+}
+
+var templateFuncs = template.FuncMap{
+	"formatTime":         formatTime,
+	"shortSHA":           shortSHA,
+	"summarizeSignature": summarizeSignature,
+	"safe":               safe,
+	"text":               text,
+	"cell":               cell,
+	"code":               code,
+	"fence":              fence,
+	"counts":             counts,
+	"sparkline":          sparkline,
+	"trend":              trend,
+	"failureRate":        failureRate,
+	"join":               strings.Join,
+	"percent":            func(f float64) float64 { return 100 * f },
+}
+
+type Templates struct {
+	t *template.Template
+}
+
+// DefaultTemplates returns the embedded template set.
+func DefaultTemplates() *Templates {
+	t, err := LoadTemplates("")
+	if err != nil {
+		panic(fmt.Sprintf("invalid embedded templates: %v", err))
+	}
+	return t
+}
+
+// LoadTemplates parses the embedded templates and then every *.tmpl file in
+// dir, which replaces the embedded template of the same name. The result is
+// executed against sample data so broken templates fail at startup.
+func LoadTemplates(dir string) (*Templates, error) {
+	t, err := template.New("").Funcs(templateFuncs).Option("missingkey=error").ParseFS(defaultTemplates, "templates/*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		paths, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			name := filepath.Base(path)
+			if t.Lookup(name) == nil {
+				return nil, fmt.Errorf("template %s: unknown template name", path)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := t.New(name).Parse(string(data)); err != nil {
+				return nil, fmt.Errorf("template %s: %w", path, err)
+			}
+		}
+	}
+	tmpl := &Templates{t: t}
+	if err := tmpl.validate(); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+func (t *Templates) execute(name string, data any) (string, error) {
+	var buf bytes.Buffer
+	if err := t.t.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Title renders the issue title.
+func (t *Templates) Title(d IssueData) (string, error) {
+	title, err := t.execute(tmplTitle, d)
+	return strings.TrimSpace(title), err
+}
+
+// Body renders every managed block of the issue body, dropping the oldest
+// excerpts until it fits the body budget.
+func (t *Templates) Body(d IssueData) (string, error) {
+	for {
+		body, err := t.body(d)
+		if err != nil || len(body) <= bodyBudget || len(d.Excerpts) == 0 {
+			return body, err
+		}
+		d.Excerpts = d.Excerpts[1:]
+		d.OmittedExcerpts++
+	}
+}
+
+func (t *Templates) body(d IssueData) (string, error) {
+	blocks := make([]string, 0, len(bodyBlocks))
+	for _, b := range bodyBlocks {
+		content, err := t.execute(b.tmpl, d)
+		if err != nil {
+			return "", err
+		}
+		blocks = append(blocks, wrapBlock(b.name, content))
+	}
+	return joinBlocks(blocks...), nil
+}
+
+func (t *Templates) validate() error {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	occ := extract.Occurrence{
+		RunID: 1, RunURL: "https://example.com/run/1", Workflow: "CI", JobName: "unit", HeadSHA: "0123456789abcdef",
+		RunAttempt: 1, TestName: "TestSample", ErrorSignature: "panic: sample", Excerpt: "panic: sample", OccurredAt: at,
+	}
+	d := IssueData{
+		Fingerprint:     store.FingerprintRecord{Fingerprint: "0123456789abcdef", TestName: "TestSample", FirstSeenAt: at, LastSeenAt: at},
+		TestName:        "TestSample",
+		ShortSignature:  "panic: sample",
+		Occurrences:     []extract.Occurrence{occ},
+		Excerpts:        []extract.Occurrence{occ},
+		OmittedExcerpts: 1,
+		Classification:  classify.Result{Class: classify.ClassFlakyTest, Confidence: 0.8, Explanation: "sample", Votes: []classify.Vote{{Name: "heuristic", Class: classify.ClassFlakyTest, Confidence: 0.8, Weight: 1}}},
+		Decision:        Decision{Action: DecisionFlaky, Rule: 3, Reason: "class flaky-test, confidence ≥ 0.75"},
+		Executions:      store.TestExecutionStats{Jobs: 1, Passed: 9, Failed: 1},
+		Priority:        "low",
+		FirstSeen:       at,
+		LastSeen:        at,
+		Labels:          []string{LabelAIManaged},
+		Related:         []github.Issue{{Number: 1}},
+		Owners:          Owners{Path: "pkg/sample/sample_test.go", CodeOwners: []string{"@org/team"}, Committers: []string{"@alice"}},
+		Stats: store.OccurrenceStats{
+			Total: 1, DistinctSHAs: 1,
+			Branches:  []store.Count{{Name: "master", Count: 1}},
+			Platforms: []store.Count{{Name: "ubuntu-latest", Count: 1}},
+			Weekly:    []store.WeeklyCount{{Week: at, Count: 1}},
+		},
+		Repro:     &Repro{Package: "./pkg/sample", Run: "^TestSample$", Count: 1, Timeout: "1m", Race: true, GoVersion: "go1.22.0", Failpoint: true, FailpointEnable: "make failpoint-enable", FailpointDisable: "make failpoint-disable", Command: "go test ./pkg/sample"},
+		UpdatedAt: at,
+	}
+	checks := []struct {
+		name string
+		data any
+	}{
+		{tmplTitle, d},
+		{tmplSummary, d},
+		{tmplEvidence, d},
+		{tmplExcerpts, d},
+		{tmplNextActions, d},
+		{tmplOwners, d},
+		{tmplAutomation, d},
+		{tmplRecurrence, RecurrenceData{Notes: []Recurrence{{Key: "sample", Note: "sample"}}}},
+		{tmplReopen, ReopenData{ClosedAt: at, Occurrences: []extract.Occurrence{occ}}},
+		{tmplCloseQuiet, CloseQuietData{QuietDays: 30, LastSeen: at}},
+		{tmplMerged, MergedData{Into: 1, Fingerprint: "0123456789abcdef"}},
+		{tmplDigest, sampleDigest(at)},
+	}
+	for _, c := range checks {
+		if _, err := t.execute(c.name, c.data); err != nil {
+			return fmt.Errorf("template %s: %w", c.name, err)
+		}
+	}
+	return nil
+}
+
+func sampleDigest(at time.Time) DigestData {
+	e := DigestEntry{Fingerprint: "0123456789abcdef", TestName: "TestSample", Package: "pkg/sample", IssueNumber: 1, Count: 3, LastSeen: at, QuietFor: 30 * 24 * time.Hour}
+	return DigestData{
+		Start: at, End: at.AddDate(0, 0, 7), QuietDays: 30, TotalFailures: 3, Tests: 1,
+		Top: []DigestEntry{e}, New: []DigestEntry{e}, Regressions: []DigestEntry{e}, Fixed: []DigestEntry{e},
+		Packages: []PackageCount{{Package: "pkg/sample", Tests: 1, Failures: 3}},
+	}
+}
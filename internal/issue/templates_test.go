@@ -0,0 +1,64 @@
+package issue
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/extract"
+)
+
+func TestLoadTemplatesOverride(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, tmplTitle), []byte("[custom] {{.TestName}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := LoadTemplates(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	title, err := tmpl.Title(IssueData{TestName: "TestFoo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title != "[custom] TestFoo" {
+		t.Fatalf("unexpected title %q", title)
+	}
+	body, err := tmpl.execute(tmplMerged, MergedData{Into: 7, Fingerprint: "abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(body, "#7") {
+		t.Fatalf("expected the default merged template, got %q", body)
+	}
+}
+
+func TestLoadTemplatesRejectsInvalid(t *testing.T) {
+	cases := map[string]string{
+		tmplSummary:    "{{.NoSuchField}}",
+		tmplEvidence:   "{{if}}",
+		"unknown.tmpl": "hello",
+	}
+	for name, content := range cases {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadTemplates(dir); err == nil {
+			t.Fatalf("expected %s with %q to be rejected", name, content)
+		}
+	}
+}
+
+func TestExcerptSummaryEscapesJobName(t *testing.T) {
+	occs := []extract.Occurrence{{RunID: 7, TestName: "TestFoo", JobName: "unit | tidb & pd <linux> @ci", Excerpt: "panic: boom"}}
+	body, err := DefaultTemplates().Body(IssueData{TestName: "TestFoo", Occurrences: occs, Excerpts: excerptOccurrences(occs)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "<summary>Run 7 — unit | tidb &amp; pd &lt;linux&gt; @" + zeroWidthSpace + "ci</summary>"
+	if !strings.Contains(body, want) {
+		t.Fatalf("expected %q in:\n%s", want, body)
+	}
+}
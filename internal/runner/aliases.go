@@ -0,0 +1,170 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/config"
+	"github.com/okJiang/flaky-test-cleaner/internal/fingerprint"
+	"github.com/okJiang/flaky-test-cleaner/internal/github"
+	"github.com/okJiang/flaky-test-cleaner/internal/issue"
+	"github.com/okJiang/flaky-test-cleaner/internal/store"
+)
+
+// Merge routes every future occurrence of args[1] to args[0] and closes the
+// issue of args[1] with a pointer to the surviving one.
+func Merge(ctx context.Context, cfg config.Config, args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: merge <target-fingerprint> <source-fingerprint>")
+	}
+	st, err := openStore(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	target, err := store.ResolveFingerprint(ctx, st, args[0], "")
+	if err != nil {
+		return err
+	}
+	source := args[1]
+	if target == source {
+		return fmt.Errorf("fingerprint %s already resolves to %s", args[0], source)
+	}
+	targetRec, err := st.GetFingerprint(ctx, target)
+	if err != nil {
+		return err
+	}
+	if targetRec == nil {
+		return fmt.Errorf("fingerprint %s not found", target)
+	}
+	sourceRec, err := st.GetFingerprint(ctx, source)
+	if err != nil {
+		return err
+	}
+	if sourceRec == nil {
+		return fmt.Errorf("fingerprint %s not found", source)
+	}
+
+	if err := st.UpsertFingerprintAlias(ctx, store.FingerprintAlias{Alias: source, Canonical: target}); err != nil {
+		return err
+	}
+	moved, err := st.MoveOccurrences(ctx, source, target, "")
+	if err != nil {
+		return err
+	}
+	merged := *targetRec
+	merged.FirstSeenAt = sourceRec.FirstSeenAt
+	merged.LastSeenAt = sourceRec.LastSeenAt
+	if err := st.UpsertFingerprint(ctx, merged); err != nil {
+		return err
+	}
+	if targetRec.IssueNumber == 0 && sourceRec.IssueNumber != 0 {
+		if err := st.LinkIssue(ctx, target, sourceRec.IssueNumber); err != nil {
+			return err
+		}
+	} else if sourceRec.IssueNumber != 0 && sourceRec.IssueNumber != targetRec.IssueNumber {
+		tmpl, err := issue.LoadTemplates(cfg.TemplateDir)
+		if err != nil {
+			return fmt.Errorf("load templates: %w", err)
+		}
+		opts := issue.Options{Owner: cfg.GitHubOwner, Repo: cfg.GitHubRepo, DryRun: cfg.DryRun, Templates: tmpl}
+		if opts.Recorder, err = newRecorder(cfg); err != nil {
+			return err
+		}
+		mgr := issue.NewManager(opts)
+		gh := github.NewClient(cfg.GitHubIssueToken, cfg.RequestTimeout)
+		if cfg.DryRun {
+			log.Printf("dry-run close issue #%d as merged into #%d", sourceRec.IssueNumber, targetRec.IssueNumber)
+		}
+		if err := mgr.CloseMerged(ctx, gh, sourceRec.IssueNumber, targetRec.IssueNumber, source); err != nil {
+			return err
+		}
+	}
+	log.Printf("merged fingerprint=%s into %s (moved %d occurrences)", source, target, moved)
+	return nil
+}
+
+// Split moves the occurrences of args[0] whose error signature contains any of
+// args[1:] to a new fingerprint, which gets its own issue on the next scan.
+func Split(ctx context.Context, cfg config.Config, args []string) error {
+	if len(args) < 2 {
+		return errors.New("usage: split <fingerprint> <signature>...")
+	}
+	st, err := openStore(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	base, err := store.ResolveFingerprint(ctx, st, args[0], "")
+	if err != nil {
+		return err
+	}
+	rec, err := st.GetFingerprint(ctx, base)
+	if err != nil {
+		return err
+	}
+	if rec == nil {
+		return fmt.Errorf("fingerprint %s not found", base)
+	}
+	signatures := args[1:]
+	newFP := fingerprint.Split(base, signatures)
+	if err := st.UpsertFingerprint(ctx, store.FingerprintRecord{
+		Fingerprint: newFP,
+		Repo:        rec.Repo,
+		TestName:    rec.TestName,
+		Framework:   rec.Framework,
+		Class:       rec.Class,
+		Confidence:  rec.Confidence,
+		FirstSeenAt: rec.FirstSeenAt,
+		LastSeenAt:  rec.LastSeenAt,
+	}); err != nil {
+		return err
+	}
+	moved := 0
+	for _, sig := range signatures {
+		if err := st.UpsertFingerprintAlias(ctx, store.FingerprintAlias{Alias: base, Signature: sig, Canonical: newFP}); err != nil {
+			return err
+		}
+		n, err := st.MoveOccurrences(ctx, base, newFP, sig)
+		if err != nil {
+			return err
+		}
+		moved += n
+	}
+	log.Printf("split fingerprint=%s into %s (moved %d occurrences)", base, newFP, moved)
+	fmt.Println(newFP)
+	return nil
+}
+
+func ListAliases(ctx context.Context, cfg config.Config, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: aliases <fingerprint>")
+	}
+	st, err := openStore(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	aliases, err := st.ListFingerprintAliases(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	for _, a := range aliases {
+		sig := a.Signature
+		if sig == "" {
+			sig = "*"
+		}
+		fmt.Printf("%s\t%s\t%q\n", a.Alias, a.Canonical, sig)
+	}
+	resolved, err := store.ResolveFingerprint(ctx, st, args[0], "")
+	if err != nil {
+		return err
+	}
+	fmt.Printf("resolves to %s\n", resolved)
+	return nil
+}
@@ -0,0 +1,49 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/config"
+	"github.com/okJiang/flaky-test-cleaner/internal/store"
+)
+
+// Candidates lists the fingerprints held back from getting an issue, with
+// their progress toward the evidence thresholds.
+func Candidates(ctx context.Context, cfg config.Config) error {
+	st, err := openStore(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	recs, err := st.ListFingerprints(ctx)
+	if err != nil {
+		return err
+	}
+	repo := cfg.GitHubOwner + "/" + cfg.GitHubRepo
+	var candidates []store.FingerprintRecord
+	for _, rec := range recs {
+		if rec.Repo == repo && rec.Candidate && rec.IssueNumber == 0 {
+			candidates = append(candidates, rec)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].LastSeenAt.After(candidates[j].LastSeenAt) })
+
+	gate := evidenceGate(cfg)
+	since := gate.Since(time.Now())
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FINGERPRINT\tTEST\tCLASS\tLAST SEEN\tPROGRESS")
+	for _, rec := range candidates {
+		e, err := st.GetEvidence(ctx, rec.Fingerprint, since, cfg.DefaultBranch)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", rec.Fingerprint, rec.TestName, rec.Class, rec.LastSeenAt.UTC().Format("2006-01-02"), gate.Progress(e))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,74 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/config"
+	"github.com/okJiang/flaky-test-cleaner/internal/github"
+	"github.com/okJiang/flaky-test-cleaner/internal/issue"
+	"github.com/okJiang/flaky-test-cleaner/internal/store"
+)
+
+// Digest creates or updates the pinned digest issue of the current period
+// from the store.
+func Digest(ctx context.Context, cfg config.Config) error {
+	tmpl, err := issue.LoadTemplates(cfg.TemplateDir)
+	if err != nil {
+		return fmt.Errorf("load templates: %w", err)
+	}
+	st, err := openStore(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	start, end := issue.DigestPeriod(time.Now(), cfg.DigestPeriod)
+	recs, err := st.ListFingerprints(ctx)
+	if err != nil {
+		return err
+	}
+	repo := cfg.GitHubOwner + "/" + cfg.GitHubRepo
+	var own []store.FingerprintRecord
+	for _, rec := range recs {
+		if rec.Repo == repo {
+			own = append(own, rec)
+		}
+	}
+	summaries, err := st.SummarizeOccurrences(ctx, start)
+	if err != nil {
+		return err
+	}
+
+	mgr := issue.NewManager(issue.Options{Owner: cfg.GitHubOwner, Repo: cfg.GitHubRepo, DryRun: cfg.DryRun, Templates: tmpl})
+	data := mgr.BuildDigest(issue.DigestInput{
+		Start:        start,
+		End:          end,
+		Fingerprints: own,
+		Summaries:    summaries,
+		Top:          cfg.DigestTop,
+		Quiet:        time.Duration(cfg.AutoCloseDays) * 24 * time.Hour,
+	})
+	if cfg.DryRun {
+		body, err := mgr.RenderDigest(data)
+		if err != nil {
+			return err
+		}
+		log.Printf("dry-run digest %q", issue.DigestTitle(data))
+		fmt.Print(body)
+		return nil
+	}
+
+	gh := github.NewClient(cfg.GitHubIssueToken, cfg.RequestTimeout)
+	iss, err := mgr.PublishDigest(ctx, gh, data)
+	if err != nil {
+		return err
+	}
+	if err := gh.PinIssue(ctx, iss.NodeID); err != nil {
+		log.Printf("pin digest issue #%d: %v", iss.Number, err)
+	}
+	log.Printf("digest issue #%d: %d failures of %d tests", iss.Number, data.TotalFailures, data.Tests)
+	return nil
+}
@@ -0,0 +1,64 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/classify"
+	"github.com/okJiang/flaky-test-cleaner/internal/config"
+	"github.com/okJiang/flaky-test-cleaner/internal/eval"
+	"github.com/okJiang/flaky-test-cleaner/internal/extract"
+)
+
+// Eval replays the labeled corpus through the scan pipeline and compares the
+// result with the baseline stored next to it.
+func Eval(ctx context.Context, cfg config.Config) error {
+	cases, err := eval.LoadCorpus(cfg.EvalCorpus)
+	if err != nil {
+		return err
+	}
+	rules, err := classify.NewRuleWatcher(cfg.RulesFile)
+	if err != nil {
+		return fmt.Errorf("load rules: %w", err)
+	}
+	report, err := eval.Run(ctx, cases, eval.Options{
+		Repo:       cfg.GitHubOwner + "/" + cfg.GitHubRepo,
+		Extractor:  extract.NewGoTestExtractor(),
+		Classifier: newClassifier(cfg, rules),
+	})
+	if err != nil {
+		return err
+	}
+	report.Format(os.Stdout)
+
+	baselinePath := filepath.Join(cfg.EvalCorpus, "baseline.json")
+	if cfg.EvalUpdateBaseline {
+		log.Printf("writing baseline %s", baselinePath)
+		return eval.WriteReport(baselinePath, report)
+	}
+	baseline, err := eval.LoadReport(baselinePath)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Printf("no baseline at %s; run with --update-baseline to create one", baselinePath)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	diff, regressed := eval.Diff(baseline, report)
+	if len(diff) == 0 {
+		fmt.Println("no change against baseline")
+		return nil
+	}
+	fmt.Println("changes against baseline:")
+	for _, line := range diff {
+		fmt.Printf("  %s\n", line)
+	}
+	if regressed {
+		return eval.ErrRegressed
+	}
+	return nil
+}
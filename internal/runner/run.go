@@ -2,21 +2,55 @@ package runner
 
 import (
 	"context"
+	"fmt"
+	"log"
 	"time"
 
+	"github.com/okJiang/flaky-test-cleaner/internal/classify"
 	"github.com/okJiang/flaky-test-cleaner/internal/config"
+	"github.com/okJiang/flaky-test-cleaner/internal/issue"
 )
 
 func Run(ctx context.Context, cfg config.Config) error {
+	switch cfg.Command {
+	case "", "scan":
+	case "merge":
+		return Merge(ctx, cfg, cfg.Args)
+	case "split":
+		return Split(ctx, cfg, cfg.Args)
+	case "aliases":
+		return ListAliases(ctx, cfg, cfg.Args)
+	case "eval":
+		return Eval(ctx, cfg)
+	case "digest":
+		return Digest(ctx, cfg)
+	case "candidates":
+		return Candidates(ctx, cfg)
+	default:
+		return fmt.Errorf("unknown command %q", cfg.Command)
+	}
+
+	rules, err := classify.NewRuleWatcher(cfg.RulesFile)
+	if err != nil {
+		return fmt.Errorf("load rules: %w", err)
+	}
+	tmpl, err := issue.LoadTemplates(cfg.TemplateDir)
+	if err != nil {
+		return fmt.Errorf("load templates: %w", err)
+	}
+	profile, err := issue.LoadRepoProfile(cfg.RepoProfile)
+	if err != nil {
+		return fmt.Errorf("load repo profile: %w", err)
+	}
 	if cfg.RunInterval <= 0 {
-		return RunOnce(ctx, cfg)
+		return runOnce(ctx, cfg, rules, tmpl, profile)
 	}
 
 	ticker := time.NewTicker(cfg.RunInterval)
 	defer ticker.Stop()
 
 	for {
-		if err := RunOnce(ctx, cfg); err != nil {
+		if err := runOnce(ctx, cfg, rules, tmpl, profile); err != nil {
 			return err
 		}
 
@@ -25,5 +59,10 @@ func Run(ctx context.Context, cfg config.Config) error {
 			return ctx.Err()
 		case <-ticker.C:
 		}
+		if reloaded, err := rules.Reload(); err != nil {
+			log.Printf("keeping previous rules: %v", err)
+		} else if reloaded {
+			log.Printf("reloaded rules from %s", cfg.RulesFile)
+		}
 	}
 }
@@ -5,11 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"path/filepath"
 	"time"
 
 	"github.com/okJiang/flaky-test-cleaner/internal/classify"
 	"github.com/okJiang/flaky-test-cleaner/internal/config"
 	"github.com/okJiang/flaky-test-cleaner/internal/extract"
+	"github.com/okJiang/flaky-test-cleaner/internal/feedback"
 	"github.com/okJiang/flaky-test-cleaner/internal/fingerprint"
 	"github.com/okJiang/flaky-test-cleaner/internal/github"
 	"github.com/okJiang/flaky-test-cleaner/internal/issue"
@@ -17,7 +19,47 @@ import (
 	"github.com/okJiang/flaky-test-cleaner/internal/store"
 )
 
+// recurrenceHistory bounds how many past occurrences are compared against a
+// new one when deciding whether to comment.
+const recurrenceHistory = 200
+
+// statsWeeks is how many weeks, including the current one, the weekly
+// failure counts in the issue Summary cover.
+const statsWeeks = 12
+
+type scanner struct {
+	cfg        config.Config
+	repo       string
+	ghRead     *github.Client
+	ghIssue    *github.Client
+	st         store.Store
+	wf         github.Workflow
+	extractor  extract.Extractor
+	classifier classify.Classifier
+	gate       issue.EvidenceGate
+	issueMgr   *issue.Manager
+	// written holds the fingerprints whose issue this scan created, updated
+	// or reopened; they are not auto-closed in the same scan.
+	written map[string]bool
+}
+
 func RunOnce(ctx context.Context, cfg config.Config) error {
+	rules, err := classify.NewRuleWatcher(cfg.RulesFile)
+	if err != nil {
+		return fmt.Errorf("load rules: %w", err)
+	}
+	tmpl, err := issue.LoadTemplates(cfg.TemplateDir)
+	if err != nil {
+		return fmt.Errorf("load templates: %w", err)
+	}
+	profile, err := issue.LoadRepoProfile(cfg.RepoProfile)
+	if err != nil {
+		return fmt.Errorf("load repo profile: %w", err)
+	}
+	return runOnce(ctx, cfg, rules, tmpl, profile)
+}
+
+func runOnce(ctx context.Context, cfg config.Config, rules classify.RuleSource, tmpl *issue.Templates, profile issue.RepoProfile) error {
 	ctx, cancel := context.WithTimeout(ctx, 30*time.Minute)
 	defer cancel()
 
@@ -27,19 +69,11 @@ func RunOnce(ctx context.Context, cfg config.Config) error {
 		ghIssue = github.NewClient(cfg.GitHubIssueToken, cfg.RequestTimeout)
 	}
 
-	var st store.Store = store.NewMemory()
-	if cfg.TiDBEnabled {
-		tidb, err := store.NewTiDBStore(cfg)
-		if err != nil {
-			return err
-		}
-		defer tidb.Close()
-		st = tidb
-	}
-
-	if err := st.Migrate(ctx); err != nil {
-		return fmt.Errorf("migrate: %w", err)
+	st, err := openStore(ctx, cfg)
+	if err != nil {
+		return err
 	}
+	defer st.Close()
 
 	wf, err := ghRead.FindWorkflowByName(ctx, cfg.GitHubOwner, cfg.GitHubRepo, cfg.WorkflowName)
 	if err != nil {
@@ -53,125 +87,666 @@ func RunOnce(ctx context.Context, cfg config.Config) error {
 	if err != nil {
 		return err
 	}
-
-	extractor := extract.NewGoTestExtractor()
-	classifier := classify.NewHeuristic(cfg.ConfidenceThreshold)
-	issueMgr := issue.NewManager(issue.Options{
-		Owner:  cfg.GitHubOwner,
-		Repo:   cfg.GitHubRepo,
-		DryRun: cfg.DryRun,
+	// Runs that eventually succeeded only show up here; their earlier attempts
+	// carry the fail-then-pass evidence.
+	passed, err := ghRead.ListWorkflowRuns(ctx, cfg.GitHubOwner, cfg.GitHubRepo, wf.ID, github.ListWorkflowRunsOptions{
+		Status:  "success",
+		PerPage: cfg.MaxRuns,
 	})
+	if err != nil {
+		return err
+	}
+	var reruns []github.WorkflowRun
+	for _, run := range passed {
+		if run.RunAttempt > 1 {
+			reruns = append(reruns, run)
+		}
+	}
 
-	for _, run := range runs {
-		jobs, err := ghRead.ListRunJobs(ctx, cfg.GitHubOwner, cfg.GitHubRepo, run.ID, github.ListRunJobsOptions{PerPage: cfg.MaxJobs})
-		if err != nil {
+	recorder, err := newRecorder(cfg)
+	if err != nil {
+		return err
+	}
+
+	s := &scanner{
+		cfg:        cfg,
+		repo:       cfg.GitHubOwner + "/" + cfg.GitHubRepo,
+		ghRead:     ghRead,
+		ghIssue:    ghIssue,
+		st:         st,
+		wf:         wf,
+		extractor:  extract.NewGoTestExtractor(),
+		classifier: newClassifier(cfg, rules),
+		gate:       evidenceGate(cfg),
+		written:    map[string]bool{},
+		issueMgr: issue.NewManager(issue.Options{
+			Owner:             cfg.GitHubOwner,
+			Repo:              cfg.GitHubRepo,
+			DryRun:            cfg.DryRun,
+			MinUpdateInterval: cfg.MinUpdateInterval,
+			QuietPeriod:       cfg.QuietPeriod,
+			SpikeFactor:       cfg.SpikeFactor,
+			CommentInterval:   cfg.CommentInterval,
+			HumanIssueQuery:   cfg.HumanIssueQuery,
+			AutoAssign:        cfg.AutoAssign,
+			Templates:         tmpl,
+			Profile:           profile,
+			Policy:            policy(cfg, profile),
+			Gate:              evidenceGate(cfg),
+			Recorder:          recorder,
+		}),
+	}
+	if err := s.syncIssues(ctx); err != nil {
+		return err
+	}
+	if cfg.BaselineEnabled {
+		for i, run := range passed {
+			if i >= cfg.BaselineRuns {
+				break
+			}
+			if err := s.sampleBaseline(ctx, run); err != nil {
+				return err
+			}
+		}
+	}
+	for _, run := range append(runs, reruns...) {
+		if err := s.scanRun(ctx, run); err != nil {
 			return err
 		}
-		for _, job := range jobs {
-			if job.Conclusion != "failure" {
+	}
+	return s.closeQuietIssues(ctx)
+}
+
+// syncIssues records the state of every linked issue and the maintainer
+// verdicts on it, so this scan's occurrences see both.
+func (s *scanner) syncIssues(ctx context.Context) error {
+	recs, err := s.st.ListFingerprints(ctx)
+	if err != nil {
+		return err
+	}
+	botLogin, err := s.botLogin(ctx)
+	if err != nil {
+		return err
+	}
+	// Without the bot's login its own label changes would read as maintainer
+	// verdicts, so feedback is not collected at all.
+	var collector *feedback.Collector
+	if botLogin != "" {
+		collector = feedback.NewCollector(s.ghRead, feedback.Options{
+			Owner:    s.cfg.GitHubOwner,
+			Repo:     s.cfg.GitHubRepo,
+			BotLogin: botLogin,
+		})
+	}
+	for _, rec := range recs {
+		if rec.IssueNumber == 0 || rec.Repo != s.repo {
+			continue
+		}
+		iss, err := s.ghRead.GetIssue(ctx, s.cfg.GitHubOwner, s.cfg.GitHubRepo, rec.IssueNumber)
+		if err != nil {
+			if errors.Is(err, github.ErrNotFound) {
 				continue
 			}
-			log.Printf("scanning run=%d job=%d %q", run.ID, job.ID, job.Name)
-			raw, err := ghRead.DownloadJobLogs(ctx, cfg.GitHubOwner, cfg.GitHubRepo, job.ID)
-			if err != nil {
+			return err
+		}
+		state, closedAt := issue.IssueState(iss)
+		if err := s.st.SetIssueState(ctx, rec.Fingerprint, state, closedAt); err != nil {
+			return err
+		}
+		if collector == nil {
+			continue
+		}
+		list, err := collector.Collect(ctx, rec, iss)
+		if err != nil {
+			return err
+		}
+		for _, f := range list {
+			if err := s.st.UpsertFeedback(ctx, f); err != nil {
 				return err
 			}
-			failures := extractor.Extract(extract.Input{
-				Repo:       cfg.GitHubOwner + "/" + cfg.GitHubRepo,
-				Workflow:   wf.Name,
+		}
+	}
+	return nil
+}
+
+// botLogin is FTC_BOT_LOGIN, or the login of the issue token, which is asked
+// for even in dry-run since the read token may belong to another account.
+// It is empty, after logging why, when neither is available.
+func (s *scanner) botLogin(ctx context.Context) (string, error) {
+	if s.cfg.BotLogin != "" {
+		return s.cfg.BotLogin, nil
+	}
+	if s.cfg.GitHubIssueToken == "" {
+		log.Printf("skipping maintainer feedback: set FTC_BOT_LOGIN or FTC_GITHUB_ISSUE_TOKEN to identify the bot account")
+		return "", nil
+	}
+	u, err := github.NewClient(s.cfg.GitHubIssueToken, s.cfg.RequestTimeout).GetAuthenticatedUser(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		log.Printf("skipping maintainer feedback: cannot determine the bot login (set FTC_BOT_LOGIN): %v", err)
+		return "", nil
+	}
+	if u.Login == "" {
+		log.Printf("skipping maintainer feedback: the issue token reports no login (set FTC_BOT_LOGIN)")
+	}
+	return u.Login, nil
+}
+
+func (s *scanner) sampleBaseline(ctx context.Context, run github.WorkflowRun) error {
+	jobs, err := s.ghRead.ListRunJobs(ctx, s.cfg.GitHubOwner, s.cfg.GitHubRepo, run.ID, github.ListRunJobsOptions{PerPage: s.cfg.MaxJobs})
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		if job.Conclusion != "success" {
+			continue
+		}
+		log.Printf("sampling baseline run=%d job=%d %q", run.ID, job.ID, job.Name)
+		raw, err := s.ghRead.DownloadJobLogs(ctx, s.cfg.GitHubOwner, s.cfg.GitHubRepo, job.ID)
+		if err != nil {
+			return err
+		}
+		if err := s.recordExecutions(ctx, run, job, raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *scanner) recordExecutions(ctx context.Context, run github.WorkflowRun, job github.Job, raw []byte) error {
+	counts := extract.CountTestResults(string(raw))
+	if len(counts) == 0 {
+		return nil
+	}
+	execs := make([]store.TestExecution, 0, len(counts))
+	for name, c := range counts {
+		execs = append(execs, store.TestExecution{
+			Repo:       s.repo,
+			TestName:   name,
+			RunID:      run.ID,
+			JobID:      job.ID,
+			Passed:     c.Passed,
+			Failed:     c.Failed,
+			OccurredAt: run.CreatedAt,
+		})
+	}
+	return s.st.RecordTestExecutions(ctx, execs)
+}
+
+func (s *scanner) scanRun(ctx context.Context, run github.WorkflowRun) error {
+	attempts := run.RunAttempt
+	if attempts < 1 {
+		attempts = 1
+	}
+	for attempt := 1; attempt <= attempts; attempt++ {
+		var jobs []github.Job
+		var err error
+		if attempts == 1 {
+			jobs, err = s.ghRead.ListRunJobs(ctx, s.cfg.GitHubOwner, s.cfg.GitHubRepo, run.ID, github.ListRunJobsOptions{PerPage: s.cfg.MaxJobs})
+		} else {
+			jobs, err = s.ghRead.ListRunAttemptJobs(ctx, s.cfg.GitHubOwner, s.cfg.GitHubRepo, run.ID, attempt, github.ListRunJobsOptions{PerPage: s.cfg.MaxJobs})
+		}
+		if err != nil {
+			return err
+		}
+		for _, job := range jobs {
+			if err := s.st.UpsertJobAttempt(ctx, store.JobAttempt{
 				RunID:      run.ID,
-				RunURL:     run.HTMLURL,
-				HeadSHA:    run.HeadSHA,
+				Attempt:    attempt,
 				JobID:      job.ID,
 				JobName:    job.Name,
-				RunnerOS:   job.RunnerOS,
-				OccurredAt: time.Now(),
-				RawLogText: string(raw),
-			})
-			if len(failures) == 0 {
+				HeadSHA:    run.HeadSHA,
+				Conclusion: job.Conclusion,
+			}); err != nil {
+				return err
+			}
+		}
+		for _, job := range jobs {
+			if job.Conclusion != "failure" {
 				continue
 			}
+			if err := s.scanJob(ctx, run, attempt, job); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
 
-			for _, occ := range failures {
-				occ.Excerpt = sanitize.Scrub(occ.Excerpt)
-				occ.ErrorSignature = fingerprint.NormalizeErrorSignature(occ.ErrorSignature)
-				fp := fingerprint.V1(fingerprint.V1Input{
-					Repo:         cfg.GitHubOwner + "/" + cfg.GitHubRepo,
-					Framework:    occ.Framework,
-					TestName:     occ.TestName,
-					ErrorSigNorm: occ.ErrorSignature,
-					Platform:     occ.PlatformBucket(),
-				})
-				occ.Fingerprint = fp
-
-				if err := st.UpsertOccurrence(ctx, occ); err != nil {
-					return err
-				}
+func (s *scanner) scanJob(ctx context.Context, run github.WorkflowRun, attempt int, job github.Job) error {
+	log.Printf("scanning run=%d attempt=%d job=%d %q", run.ID, attempt, job.ID, job.Name)
+	raw, err := s.ghRead.DownloadJobLogs(ctx, s.cfg.GitHubOwner, s.cfg.GitHubRepo, job.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.recordExecutions(ctx, run, job, raw); err != nil {
+		return err
+	}
+	failures := s.extractor.Extract(extract.Input{
+		Repo:       s.repo,
+		Workflow:   s.wf.Name,
+		RunID:      run.ID,
+		RunURL:     run.HTMLURL,
+		HeadSHA:    run.HeadSHA,
+		RunAttempt: attempt,
+		Branch:     run.HeadBranch,
+		Event:      run.Event,
+		JobID:      job.ID,
+		JobName:    job.Name,
+		RunnerOS:   job.RunnerOS,
+		OccurredAt: run.CreatedAt,
+		RawLogText: string(raw),
+	})
+	for _, occ := range failures {
+		if err := s.handleOccurrence(ctx, occ); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-				c, err := classifier.Classify(ctx, st, occ)
-				if err != nil {
-					return err
-				}
+func (s *scanner) handleOccurrence(ctx context.Context, occ extract.Occurrence) error {
+	occ.Excerpt = sanitize.Scrub(occ.Excerpt)
+	occ = fingerprint.ForOccurrence(s.repo, occ)
+	fp, err := store.ResolveFingerprint(ctx, s.st, occ.Fingerprint, occ.ErrorSignature)
+	if err != nil {
+		return err
+	}
+	occ.Fingerprint = fp
 
-				if err := st.UpsertFingerprint(ctx, store.FingerprintRecord{
-					Fingerprint: fp,
-					Repo:        cfg.GitHubOwner + "/" + cfg.GitHubRepo,
-					TestName:    occ.TestName,
-					Framework:   occ.Framework,
-					Class:       string(c.Class),
-					Confidence:  c.Confidence,
-					FirstSeenAt: occ.OccurredAt,
-					LastSeenAt:  occ.OccurredAt,
-				}); err != nil {
-					return err
-				}
+	if err := s.st.UpsertOccurrence(ctx, occ); err != nil {
+		return err
+	}
 
-				if c.Class == classify.ClassInfraFlake {
-					continue
-				}
+	c, err := s.classifier.Classify(ctx, s.st, occ)
+	if err != nil {
+		return err
+	}
 
-				fpRec, err := st.GetFingerprint(ctx, fp)
-				if err != nil {
-					return err
-				}
-				if fpRec == nil {
-					return errors.New("fingerprint record missing after upsert")
-				}
+	if err := s.st.UpsertFingerprint(ctx, store.FingerprintRecord{
+		Fingerprint: fp,
+		Repo:        s.repo,
+		TestName:    occ.TestName,
+		Framework:   occ.Framework,
+		Class:       string(c.Class),
+		Confidence:  c.Confidence,
+		FirstSeenAt: occ.OccurredAt,
+		LastSeenAt:  occ.OccurredAt,
+	}); err != nil {
+		return err
+	}
 
-				recent, err := st.ListRecentOccurrences(ctx, fp, 5)
-				if err != nil {
-					return err
-				}
+	stats, err := s.st.GetOccurrenceStats(ctx, fp, time.Now().AddDate(0, 0, -7*(statsWeeks-1)))
+	if err != nil {
+		return err
+	}
+	decision := s.issueMgr.Decide(c, stats)
+	if decision.Action == issue.DecisionIgnore {
+		return nil
+	}
+	verdicts, err := s.st.ListFeedback(ctx, fp)
+	if err != nil {
+		return err
+	}
+	if fb, ok := store.LatestVerdict(verdicts); ok && fb.Verdict == store.VerdictNotFlaky {
+		log.Printf("skipping issue update fingerprint=%s: @%s marked it not flaky on #%d", fp, fb.Actor, fb.IssueNumber)
+		return nil
+	}
 
-				change, err := issueMgr.PlanIssueUpdate(issue.PlanInput{
-					Fingerprint:    *fpRec,
-					Occurrences:    recent,
-					Classification: c,
-				})
-				if err != nil {
+	fpRec, err := s.st.GetFingerprint(ctx, fp)
+	if err != nil {
+		return err
+	}
+	if fpRec == nil {
+		return errors.New("fingerprint record missing after upsert")
+	}
+	evidence, err := s.st.GetEvidence(ctx, fp, s.gate.Since(time.Now()), s.cfg.DefaultBranch)
+	if err != nil {
+		return err
+	}
+	if fpRec.IssueNumber == 0 {
+		// Nothing about an issue is looked up for a fingerprint that would
+		// not get one, so neither can a relinked or adopted issue bypass the
+		// policy and evidence gates.
+		if reason, candidate := s.issueMgr.HoldBack(decision, evidence); reason != "" {
+			if candidate != fpRec.Candidate {
+				if err := s.st.SetCandidate(ctx, fp, candidate); err != nil {
 					return err
 				}
+			}
+			log.Printf("no issue for fingerprint=%s: %s", fp, reason)
+			return nil
+		}
+		if err := s.relinkIssue(ctx, fpRec); err != nil {
+			return err
+		}
+	}
+	related, err := s.humanIssues(ctx, fpRec, decision)
+	if err != nil {
+		return err
+	}
+	reopened := false
+	if fpRec.IssueNumber != 0 {
+		switch fpRec.IssueState {
+		case store.IssueStateNotPlanned:
+			log.Printf("issue #%d closed as not planned; only recording fingerprint=%s", fpRec.IssueNumber, fp)
+			return nil
+		case store.IssueStateClosed:
+			if !occ.OccurredAt.After(fpRec.IssueClosedAt) {
+				return nil
+			}
+			if err := s.reopen(ctx, fpRec); err != nil {
+				return err
+			}
+			reopened = true
+			s.written[fp] = true
+		}
+	}
 
-				if change.Noop {
-					continue
-				}
-				
-				if cfg.DryRun {
-					log.Printf("dry-run issue update fingerprint=%s title=%q labels=%v", fp, change.Title, change.Labels)
-				}
+	recent, err := s.st.ListRecentOccurrences(ctx, fp, 5)
+	if err != nil {
+		return err
+	}
+	attempts, err := s.listAttempts(ctx, recent)
+	if err != nil {
+		return err
+	}
+	execs, err := s.st.GetTestExecutionStats(ctx, s.repo, occ.TestName, time.Now().Add(-s.cfg.HistoryWindow))
+	if err != nil {
+		return err
+	}
+	owners, err := s.issueMgr.SuggestOwners(ctx, s.ghRead, occ)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// Owners are best-effort: the issue is still worth updating.
+		log.Printf("suggest owners fingerprint=%s: %v", fp, err)
+		owners = issue.Owners{}
+	}
 
-				issueNumber, err := issueMgr.Apply(ctx, ghIssue, change)
-				if err != nil {
+	change, err := s.issueMgr.PlanIssueUpdate(issue.PlanInput{
+		Fingerprint:    *fpRec,
+		Occurrences:    recent,
+		Classification: c,
+		Attempts:       attempts,
+		Executions:     execs,
+		Related:        related,
+		Stats:          stats,
+		Owners:         owners,
+		Evidence:       evidence,
+	})
+	if err != nil {
+		return err
+	}
+	if change.Candidate != fpRec.Candidate {
+		if err := s.st.SetCandidate(ctx, fp, change.Candidate); err != nil {
+			return err
+		}
+	}
+
+	if change.Noop {
+		if change.IssueNumber != 0 {
+			log.Printf("issue #%d not updated fingerprint=%s: %s", change.IssueNumber, fp, change.Reason)
+		}
+	} else {
+		if s.cfg.DryRun {
+			log.Printf("dry-run issue update fingerprint=%s title=%q labels=%v", fp, change.Title, change.Labels)
+		}
+		issueNumber, err := s.issueMgr.Apply(ctx, s.ghIssue, change)
+		var assignErr *issue.AssignError
+		if errors.As(err, &assignErr) && ctx.Err() == nil {
+			// Assignment is best-effort, like the owner lookup: the issue
+			// was written and must still be linked.
+			log.Printf("fingerprint=%s: %v", fp, err)
+			err = nil
+		}
+		if err != nil {
+			return err
+		}
+		s.written[fp] = true
+		if issueNumber != 0 {
+			if err := s.st.LinkIssue(ctx, fp, issueNumber); err != nil {
+				return err
+			}
+			if change.Create {
+				if err := s.st.SetIssueState(ctx, fp, store.IssueStateOpen, time.Time{}); err != nil {
 					return err
 				}
-				if issueNumber != 0 {
-					if err := st.LinkIssue(ctx, fp, issueNumber); err != nil {
-						return err
-					}
-				}
+			}
+			if err := s.st.RecordIssueUpdate(ctx, fp, change.ContentHash, time.Now()); err != nil {
+				return err
 			}
 		}
 	}
+	if reopened {
+		// The reopen comment already announced this occurrence.
+		return nil
+	}
+	return s.notifyRecurrence(ctx, fpRec.IssueNumber, occ)
+}
 
+// relinkIssue links rec to an existing issue carrying its fingerprint marker,
+// if any, so that issue creation does not depend on the store being intact.
+func (s *scanner) relinkIssue(ctx context.Context, rec *store.FingerprintRecord) error {
+	iss, ok, err := s.issueMgr.FindIssue(ctx, s.ghRead, rec.Fingerprint)
+	if err != nil || !ok {
+		return err
+	}
+	log.Printf("relinking fingerprint=%s to existing issue #%d", rec.Fingerprint, iss.Number)
+	if err := s.st.LinkIssue(ctx, rec.Fingerprint, iss.Number); err != nil {
+		return err
+	}
+	state, closedAt := issue.IssueState(iss)
+	if err := s.st.SetIssueState(ctx, rec.Fingerprint, state, closedAt); err != nil {
+		return err
+	}
+	rec.IssueNumber, rec.IssueState, rec.IssueClosedAt = iss.Number, state, closedAt
 	return nil
 }
+
+// humanIssues looks for human-filed issues about the same test. An unlinked
+// fingerprint adopts a strong match as its tracking issue when the policy
+// decision would open one; the remaining matches are returned for
+// cross-referencing. Search failures only cost the cross-references.
+func (s *scanner) humanIssues(ctx context.Context, rec *store.FingerprintRecord, decision issue.Decision) ([]github.Issue, error) {
+	matches, err := s.issueMgr.FindHumanIssues(ctx, s.ghRead, rec.TestName)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		log.Printf("search human-filed issues test=%q: %v", rec.TestName, err)
+		return nil, nil
+	}
+	if adopted := matches.Adoptable(decision); rec.IssueNumber == 0 && adopted != nil {
+		log.Printf("adopting human-filed issue #%d for fingerprint=%s", adopted.Number, rec.Fingerprint)
+		if err := s.st.LinkIssue(ctx, rec.Fingerprint, adopted.Number); err != nil {
+			return nil, err
+		}
+		if err := s.st.SetIssueState(ctx, rec.Fingerprint, store.IssueStateOpen, time.Time{}); err != nil {
+			return nil, err
+		}
+		rec.IssueNumber, rec.IssueState = adopted.Number, store.IssueStateOpen
+	}
+	var related []github.Issue
+	if matches.Strong != nil && matches.Strong.Number != rec.IssueNumber {
+		related = append(related, *matches.Strong)
+	}
+	for _, iss := range matches.Weak {
+		if iss.Number != rec.IssueNumber {
+			related = append(related, iss)
+		}
+	}
+	return related, nil
+}
+
+// reopen reopens a fixed issue whose fingerprint failed after it was closed.
+func (s *scanner) reopen(ctx context.Context, rec *store.FingerprintRecord) error {
+	if s.cfg.DryRun {
+		log.Printf("dry-run reopen issue #%d: regressed after fix fingerprint=%s", rec.IssueNumber, rec.Fingerprint)
+	}
+	recent, err := s.st.ListRecentOccurrences(ctx, rec.Fingerprint, 5)
+	if err != nil {
+		return err
+	}
+	if err := s.issueMgr.Reopen(ctx, s.ghIssue, rec.IssueNumber, rec.IssueClosedAt, recent); err != nil {
+		return err
+	}
+	if s.cfg.DryRun {
+		return nil
+	}
+	rec.IssueState, rec.IssueClosedAt = store.IssueStateOpen, time.Time{}
+	return s.st.SetIssueState(ctx, rec.Fingerprint, store.IssueStateOpen, time.Time{})
+}
+
+// closeQuietIssues closes open issues whose fingerprint has not failed for
+// AutoCloseDays, except those this scan wrote: a backfill of old runs may
+// create an issue whose occurrences are all older than that.
+func (s *scanner) closeQuietIssues(ctx context.Context) error {
+	if s.cfg.AutoCloseDays <= 0 {
+		return nil
+	}
+	quiet := time.Duration(s.cfg.AutoCloseDays) * 24 * time.Hour
+	recs, err := s.st.ListFingerprints(ctx)
+	if err != nil {
+		return err
+	}
+	for _, rec := range recs {
+		if rec.IssueNumber == 0 || rec.Repo != s.repo || rec.IssueState != store.IssueStateOpen {
+			continue
+		}
+		if rec.LastSeenAt.IsZero() || time.Since(rec.LastSeenAt) < quiet || s.written[rec.Fingerprint] {
+			continue
+		}
+		if s.cfg.DryRun {
+			log.Printf("dry-run close issue #%d: quiet since %s fingerprint=%s", rec.IssueNumber, rec.LastSeenAt.Format(time.RFC3339), rec.Fingerprint)
+		}
+		if err := s.issueMgr.CloseQuiet(ctx, s.ghIssue, rec.IssueNumber, rec.LastSeenAt, quiet); err != nil {
+			return err
+		}
+		if s.cfg.DryRun {
+			continue
+		}
+		if err := s.st.SetIssueState(ctx, rec.Fingerprint, store.IssueStateClosed, time.Now()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notifyRecurrence comments on an existing issue when occ is news to
+// watchers; body edits alone do not notify anyone.
+func (s *scanner) notifyRecurrence(ctx context.Context, issueNumber int, occ extract.Occurrence) error {
+	if issueNumber == 0 {
+		return nil
+	}
+	history, err := s.st.ListRecentOccurrences(ctx, occ.Fingerprint, recurrenceHistory)
+	if err != nil {
+		return err
+	}
+	recs := s.issueMgr.DetectRecurrence(occ, history)
+	if len(recs) == 0 {
+		return nil
+	}
+	if s.cfg.DryRun {
+		for _, r := range recs {
+			log.Printf("dry-run recurrence comment issue=#%d: %s", issueNumber, r.Note)
+		}
+	}
+	return s.issueMgr.PostRecurrence(ctx, s.ghIssue, issueNumber, recs)
+}
+
+// policy is the repository profile's policy, or the default one tuned by the
+// configuration.
+func policy(cfg config.Config, profile issue.RepoProfile) issue.Policy {
+	if len(profile.Policy.Rules) > 0 {
+		return profile.Policy
+	}
+	return issue.DefaultPolicy(cfg.ConfidenceThreshold, cfg.NeedsTriage)
+}
+
+func evidenceGate(cfg config.Config) issue.EvidenceGate {
+	gate := issue.EvidenceGate{MinOccurrences: cfg.MinOccurrences, MinDistinctSHAs: cfg.MinDistinctSHAs, Window: cfg.EvidenceWindow}
+	if cfg.RequireDefaultBranch {
+		gate.Branch = cfg.DefaultBranch
+	}
+	return gate
+}
+
+// newRecorder returns a recorder writing to a fresh subdirectory of
+// DryRunDir, or nil unless dry-run output is enabled.
+func newRecorder(cfg config.Config) (*issue.Recorder, error) {
+	if !cfg.DryRun || cfg.DryRunDir == "" {
+		return nil, nil
+	}
+	rec, err := issue.NewRecorder(filepath.Join(cfg.DryRunDir, time.Now().UTC().Format("20060102T150405Z")))
+	if err != nil {
+		return nil, fmt.Errorf("dry-run output: %w", err)
+	}
+	log.Printf("dry-run output in %s", rec.Dir())
+	return rec, nil
+}
+
+func (s *scanner) listAttempts(ctx context.Context, occs []extract.Occurrence) (map[int64][]store.JobAttempt, error) {
+	out := map[int64][]store.JobAttempt{}
+	for _, occ := range occs {
+		if _, ok := out[occ.RunID]; ok {
+			continue
+		}
+		list, err := s.st.ListJobAttempts(ctx, occ.RunID)
+		if err != nil {
+			return nil, err
+		}
+		out[occ.RunID] = list
+	}
+	return out, nil
+}
+
+func newClassifier(cfg config.Config, rules classify.RuleSource) classify.Classifier {
+	members := []classify.Member{
+		{Name: "feedback", Classifier: classify.NewFeedback(), Weight: cfg.FeedbackWeight},
+		{Name: "heuristic", Classifier: classify.NewHeuristicWithRules(rules), Weight: cfg.HeuristicWeight},
+		{Name: "history", Classifier: classify.NewHistory(classify.HistoryOptions{
+			Window:        cfg.HistoryWindow,
+			DefaultBranch: cfg.DefaultBranch,
+			Baseline:      cfg.BaselineEnabled,
+		}), Weight: cfg.HistoryWeight},
+	}
+	if cfg.LLMEnabled {
+		members = append(members, classify.Member{
+			Name: "llm",
+			Classifier: classify.NewLLMClassifier(classify.LLMOptions{
+				BaseURL:    cfg.LLMBaseURL,
+				APIKey:     cfg.LLMAPIKey,
+				Model:      cfg.LLMModel,
+				Timeout:    cfg.LLMTimeout,
+				MaxRetries: cfg.LLMMaxRetries,
+			}),
+			Weight: cfg.LLMWeight,
+		})
+	}
+	return classify.NewComposite(members, []classify.Override{
+		{Member: "feedback", MinConfidence: 1.0},
+		{Member: "heuristic", Class: classify.ClassInfraFlake, MinConfidence: cfg.InfraOverrideConfidence},
+		{Member: "heuristic", Class: classify.ClassLikelyRegression, MinConfidence: cfg.RegressionOverrideConfidence},
+		{Member: "history", Class: classify.ClassLikelyRegression, MinConfidence: cfg.RegressionOverrideConfidence},
+	})
+}
+
+func openStore(ctx context.Context, cfg config.Config) (store.Store, error) {
+	var st store.Store = store.NewMemory()
+	if cfg.TiDBEnabled {
+		tidb, err := store.NewTiDBStore(cfg)
+		if err != nil {
+			return nil, err
+		}
+		st = tidb
+	}
+	if err := st.Migrate(ctx); err != nil {
+		_ = st.Close()
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+	return st, nil
+}
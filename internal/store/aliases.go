@@ -0,0 +1,142 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/extract"
+)
+
+// FingerprintAlias routes occurrences of Alias to Canonical. When Signature is
+// set, only occurrences whose normalized error signature contains it are routed.
+type FingerprintAlias struct {
+	Alias     string
+	Signature string
+	Canonical string
+	CreatedAt time.Time
+}
+
+// ResolveFingerprint follows alias chains from fingerprint until it reaches a
+// fingerprint without a matching alias.
+func ResolveFingerprint(ctx context.Context, st Store, fingerprint, signature string) (string, error) {
+	seen := map[string]struct{}{fingerprint: {}}
+	for {
+		aliases, err := st.ListFingerprintAliases(ctx, fingerprint)
+		if err != nil {
+			return "", err
+		}
+		next := pickAlias(aliases, signature)
+		if next == "" {
+			return fingerprint, nil
+		}
+		if _, ok := seen[next]; ok {
+			return "", fmt.Errorf("fingerprint alias cycle at %s", next)
+		}
+		seen[next] = struct{}{}
+		fingerprint = next
+	}
+}
+
+func pickAlias(aliases []FingerprintAlias, signature string) string {
+	fallback := ""
+	best := ""
+	bestLen := 0
+	for _, a := range aliases {
+		if a.Signature == "" {
+			fallback = a.Canonical
+			continue
+		}
+		if strings.Contains(signature, a.Signature) && len(a.Signature) > bestLen {
+			best = a.Canonical
+			bestLen = len(a.Signature)
+		}
+	}
+	if best != "" {
+		return best
+	}
+	return fallback
+}
+
+func (m *Memory) MoveOccurrences(ctx context.Context, from, to, signature string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var keep []extract.Occurrence
+	moved := 0
+	for _, occ := range m.occurrences[from] {
+		if signature != "" && !strings.Contains(occ.ErrorSignature, signature) {
+			keep = append(keep, occ)
+			continue
+		}
+		occ.Fingerprint = to
+		m.occurrences[to] = append(m.occurrences[to], occ)
+		moved++
+	}
+	m.occurrences[from] = keep
+	return moved, nil
+}
+
+func (m *Memory) UpsertFingerprintAlias(ctx context.Context, alias FingerprintAlias) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if alias.CreatedAt.IsZero() {
+		alias.CreatedAt = time.Now()
+	}
+	list := m.aliases[alias.Alias]
+	for i, a := range list {
+		if a.Signature == alias.Signature {
+			list[i] = alias
+			return nil
+		}
+	}
+	m.aliases[alias.Alias] = append(list, alias)
+	return nil
+}
+
+func (m *Memory) ListFingerprintAliases(ctx context.Context, fingerprint string) ([]FingerprintAlias, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]FingerprintAlias, len(m.aliases[fingerprint]))
+	copy(out, m.aliases[fingerprint])
+	return out, nil
+}
+
+func (t *TiDBStore) MoveOccurrences(ctx context.Context, from, to, signature string) (int, error) {
+	res, err := t.db.ExecContext(ctx, `UPDATE occurrences SET fingerprint = ?
+		WHERE fingerprint = ? AND (? = '' OR LOCATE(?, error_signature) > 0)`, to, from, signature, signature)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func (t *TiDBStore) UpsertFingerprintAlias(ctx context.Context, alias FingerprintAlias) error {
+	if alias.CreatedAt.IsZero() {
+		alias.CreatedAt = time.Now()
+	}
+	_, err := t.db.ExecContext(ctx, `INSERT INTO fingerprint_aliases (alias, signature, canonical, created_at)
+		VALUES (?,?,?,?)
+		ON DUPLICATE KEY UPDATE canonical = VALUES(canonical), created_at = VALUES(created_at)`,
+		alias.Alias, alias.Signature, alias.Canonical, alias.CreatedAt)
+	return err
+}
+
+func (t *TiDBStore) ListFingerprintAliases(ctx context.Context, fingerprint string) ([]FingerprintAlias, error) {
+	rows, err := t.db.QueryContext(ctx, `SELECT alias, signature, canonical, created_at
+		FROM fingerprint_aliases WHERE alias = ? ORDER BY created_at`, fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []FingerprintAlias
+	for rows.Next() {
+		var a FingerprintAlias
+		if err := rows.Scan(&a.Alias, &a.Signature, &a.Canonical, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
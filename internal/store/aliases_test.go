@@ -0,0 +1,36 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestResolveFingerprintFollowsAliases(t *testing.T) {
+	ctx := context.Background()
+	st := NewMemory()
+	_ = st.UpsertFingerprintAlias(ctx, FingerprintAlias{Alias: "b", Canonical: "a"})
+	_ = st.UpsertFingerprintAlias(ctx, FingerprintAlias{Alias: "a", Signature: "DATA RACE", Canonical: "c"})
+
+	got, err := ResolveFingerprint(ctx, st, "b", "panic: boom")
+	if err != nil || got != "a" {
+		t.Fatalf("expected a, got %q err=%v", got, err)
+	}
+	got, err = ResolveFingerprint(ctx, st, "b", "WARNING: DATA RACE")
+	if err != nil || got != "c" {
+		t.Fatalf("expected c, got %q err=%v", got, err)
+	}
+	got, err = ResolveFingerprint(ctx, st, "x", "")
+	if err != nil || got != "x" {
+		t.Fatalf("expected x, got %q err=%v", got, err)
+	}
+}
+
+func TestResolveFingerprintDetectsCycle(t *testing.T) {
+	ctx := context.Background()
+	st := NewMemory()
+	_ = st.UpsertFingerprintAlias(ctx, FingerprintAlias{Alias: "a", Canonical: "b"})
+	_ = st.UpsertFingerprintAlias(ctx, FingerprintAlias{Alias: "b", Canonical: "a"})
+	if _, err := ResolveFingerprint(ctx, st, "a", ""); err == nil {
+		t.Fatalf("expected cycle error")
+	}
+}
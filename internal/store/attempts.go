@@ -0,0 +1,98 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// JobAttempt is the outcome of one job in one attempt of a workflow run.
+type JobAttempt struct {
+	RunID       int64
+	Attempt     int
+	JobID       int64
+	JobName     string
+	HeadSHA     string
+	Conclusion  string
+	CompletedAt time.Time
+}
+
+// PassedOnRerun returns the first attempt after failedAttempt in which jobName
+// succeeded, or 0 when the job never passed on a later attempt.
+func PassedOnRerun(attempts []JobAttempt, jobName string, failedAttempt int) int {
+	passed := 0
+	for _, a := range attempts {
+		if a.JobName != jobName || a.Attempt <= failedAttempt || a.Conclusion != "success" {
+			continue
+		}
+		if passed == 0 || a.Attempt < passed {
+			passed = a.Attempt
+		}
+	}
+	return passed
+}
+
+func (m *Memory) UpsertJobAttempt(ctx context.Context, a JobAttempt) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := m.attempts[a.RunID]
+	for i, prev := range list {
+		if prev.Attempt == a.Attempt && prev.JobID == a.JobID {
+			list[i] = a
+			return nil
+		}
+	}
+	m.attempts[a.RunID] = append(list, a)
+	return nil
+}
+
+func (m *Memory) ListJobAttempts(ctx context.Context, runID int64) ([]JobAttempt, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]JobAttempt, len(m.attempts[runID]))
+	copy(out, m.attempts[runID])
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Attempt != out[j].Attempt {
+			return out[i].Attempt < out[j].Attempt
+		}
+		return out[i].JobID < out[j].JobID
+	})
+	return out, nil
+}
+
+func (t *TiDBStore) UpsertJobAttempt(ctx context.Context, a JobAttempt) error {
+	_, err := t.db.ExecContext(ctx, `INSERT INTO job_attempts (run_id, attempt, job_id, job_name, head_sha, conclusion, completed_at)
+		VALUES (?,?,?,?,?,?,?)
+		ON DUPLICATE KEY UPDATE conclusion = VALUES(conclusion), completed_at = VALUES(completed_at)`,
+		a.RunID, a.Attempt, a.JobID, a.JobName, a.HeadSHA, a.Conclusion, nullTime(a.CompletedAt))
+	return err
+}
+
+func (t *TiDBStore) ListJobAttempts(ctx context.Context, runID int64) ([]JobAttempt, error) {
+	rows, err := t.db.QueryContext(ctx, `SELECT run_id, attempt, job_id, job_name, head_sha, conclusion, completed_at
+		FROM job_attempts WHERE run_id = ? ORDER BY attempt, job_id`, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []JobAttempt
+	for rows.Next() {
+		var a JobAttempt
+		var completed *time.Time
+		if err := rows.Scan(&a.RunID, &a.Attempt, &a.JobID, &a.JobName, &a.HeadSHA, &a.Conclusion, &completed); err != nil {
+			return nil, err
+		}
+		if completed != nil {
+			a.CompletedAt = *completed
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+func nullTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
@@ -0,0 +1,63 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ClassificationCacheEntry keeps a validated classifier payload for one
+// (fingerprint, evidence hash) pair so identical evidence is never re-sent.
+type ClassificationCacheEntry struct {
+	Fingerprint  string
+	EvidenceHash string
+	Model        string
+	Payload      string
+	CreatedAt    time.Time
+}
+
+func (m *Memory) GetClassificationCache(ctx context.Context, fingerprint, evidenceHash string) (*ClassificationCacheEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.classifications[fingerprint+"|"+evidenceHash]
+	if !ok {
+		return nil, nil
+	}
+	cpy := entry
+	return &cpy, nil
+}
+
+func (m *Memory) PutClassificationCache(ctx context.Context, entry ClassificationCacheEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	m.classifications[entry.Fingerprint+"|"+entry.EvidenceHash] = entry
+	return nil
+}
+
+func (t *TiDBStore) GetClassificationCache(ctx context.Context, fingerprint, evidenceHash string) (*ClassificationCacheEntry, error) {
+	row := t.db.QueryRowContext(ctx, `SELECT fingerprint, evidence_hash, model, payload, created_at
+		FROM classification_cache WHERE fingerprint = ? AND evidence_hash = ?`, fingerprint, evidenceHash)
+	var entry ClassificationCacheEntry
+	if err := row.Scan(&entry.Fingerprint, &entry.EvidenceHash, &entry.Model, &entry.Payload, &entry.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (t *TiDBStore) PutClassificationCache(ctx context.Context, entry ClassificationCacheEntry) error {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	_, err := t.db.ExecContext(ctx, `INSERT INTO classification_cache (fingerprint, evidence_hash, model, payload, created_at)
+		VALUES (?,?,?,?,?)
+		ON DUPLICATE KEY UPDATE model = VALUES(model), payload = VALUES(payload), created_at = VALUES(created_at)`,
+		entry.Fingerprint, entry.EvidenceHash, entry.Model, entry.Payload, entry.CreatedAt)
+	return err
+}
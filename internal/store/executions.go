@@ -0,0 +1,92 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// TestExecution is how often a test passed or failed in one job, used as the
+// denominator for failure rates.
+type TestExecution struct {
+	Repo       string
+	TestName   string
+	RunID      int64
+	JobID      int64
+	Passed     int
+	Failed     int
+	OccurredAt time.Time
+}
+
+type TestExecutionStats struct {
+	Jobs   int
+	Passed int
+	Failed int
+}
+
+func (s TestExecutionStats) Executions() int { return s.Passed + s.Failed }
+
+func (s TestExecutionStats) FailureRate() float64 {
+	if s.Executions() == 0 {
+		return 0
+	}
+	return float64(s.Failed) / float64(s.Executions())
+}
+
+func (m *Memory) RecordTestExecutions(ctx context.Context, execs []TestExecution) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range execs {
+		key := e.Repo + "|" + e.TestName
+		list := m.executions[key]
+		replaced := false
+		for i, prev := range list {
+			if prev.RunID == e.RunID && prev.JobID == e.JobID {
+				list[i] = e
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			list = append(list, e)
+		}
+		m.executions[key] = list
+	}
+	return nil
+}
+
+func (m *Memory) GetTestExecutionStats(ctx context.Context, repo, testName string, since time.Time) (TestExecutionStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var stats TestExecutionStats
+	for _, e := range m.executions[repo+"|"+testName] {
+		if e.OccurredAt.Before(since) {
+			continue
+		}
+		stats.Jobs++
+		stats.Passed += e.Passed
+		stats.Failed += e.Failed
+	}
+	return stats, nil
+}
+
+func (t *TiDBStore) RecordTestExecutions(ctx context.Context, execs []TestExecution) error {
+	for _, e := range execs {
+		if _, err := t.db.ExecContext(ctx, `INSERT INTO test_executions (repo, test_name, run_id, job_id, passed, failed, occurred_at)
+			VALUES (?,?,?,?,?,?,?)
+			ON DUPLICATE KEY UPDATE passed = VALUES(passed), failed = VALUES(failed)`,
+			e.Repo, e.TestName, e.RunID, e.JobID, e.Passed, e.Failed, e.OccurredAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *TiDBStore) GetTestExecutionStats(ctx context.Context, repo, testName string, since time.Time) (TestExecutionStats, error) {
+	row := t.db.QueryRowContext(ctx, `SELECT COUNT(*), COALESCE(SUM(passed), 0), COALESCE(SUM(failed), 0)
+		FROM test_executions WHERE repo = ? AND test_name = ? AND occurred_at >= ?`, repo, testName, since)
+	var stats TestExecutionStats
+	if err := row.Scan(&stats.Jobs, &stats.Passed, &stats.Failed); err != nil {
+		return TestExecutionStats{}, err
+	}
+	return stats, nil
+}
@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// Maintainer verdicts collected from managed issues.
+const (
+	VerdictFlaky      = "flaky"
+	VerdictNotFlaky   = "not-flaky"
+	VerdictRegression = "regression"
+	VerdictInfra      = "infra"
+	VerdictFixed      = "fixed"
+)
+
+// Feedback is one maintainer verdict on a managed issue. SourceID identifies
+// the issue event or comment it came from so collecting again is idempotent.
+type Feedback struct {
+	Fingerprint string
+	Repo        string
+	TestName    string
+	IssueNumber int
+	SourceID    string
+	Source      string
+	Verdict     string
+	Actor       string
+	CreatedAt   time.Time
+}
+
+// LatestVerdict returns the most recent feedback that carries a
+// classification verdict; "fixed" only records how an issue was closed.
+func LatestVerdict(list []Feedback) (Feedback, bool) {
+	var latest Feedback
+	found := false
+	for _, f := range list {
+		if f.Verdict == VerdictFixed {
+			continue
+		}
+		if !found || f.CreatedAt.After(latest.CreatedAt) {
+			latest = f
+			found = true
+		}
+	}
+	return latest, found
+}
+
+func (m *Memory) UpsertFeedback(ctx context.Context, f Feedback) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := m.feedback[f.Fingerprint]
+	for i, prev := range list {
+		if prev.SourceID == f.SourceID {
+			list[i] = f
+			return nil
+		}
+	}
+	m.feedback[f.Fingerprint] = append(list, f)
+	return nil
+}
+
+func (m *Memory) ListFeedback(ctx context.Context, fingerprint string) ([]Feedback, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Feedback, len(m.feedback[fingerprint]))
+	copy(out, m.feedback[fingerprint])
+	sortFeedback(out)
+	return out, nil
+}
+
+func (m *Memory) ListFeedbackByTest(ctx context.Context, repo, testName string) ([]Feedback, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []Feedback
+	for _, list := range m.feedback {
+		for _, f := range list {
+			if f.Repo == repo && f.TestName == testName {
+				out = append(out, f)
+			}
+		}
+	}
+	sortFeedback(out)
+	return out, nil
+}
+
+func sortFeedback(list []Feedback) {
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+}
+
+func (t *TiDBStore) UpsertFeedback(ctx context.Context, f Feedback) error {
+	_, err := t.db.ExecContext(ctx, `INSERT INTO feedback (fingerprint, source_id, repo, test_name, issue_number, source, verdict, actor, created_at)
+		VALUES (?,?,?,?,?,?,?,?,?)
+		ON DUPLICATE KEY UPDATE verdict = VALUES(verdict), actor = VALUES(actor), created_at = VALUES(created_at)`,
+		f.Fingerprint, f.SourceID, f.Repo, f.TestName, f.IssueNumber, f.Source, f.Verdict, f.Actor, f.CreatedAt)
+	return err
+}
+
+func (t *TiDBStore) ListFeedback(ctx context.Context, fingerprint string) ([]Feedback, error) {
+	return t.queryFeedback(ctx, `WHERE fingerprint = ?`, fingerprint)
+}
+
+func (t *TiDBStore) ListFeedbackByTest(ctx context.Context, repo, testName string) ([]Feedback, error) {
+	return t.queryFeedback(ctx, `WHERE repo = ? AND test_name = ?`, repo, testName)
+}
+
+func (t *TiDBStore) queryFeedback(ctx context.Context, where string, args ...any) ([]Feedback, error) {
+	rows, err := t.db.QueryContext(ctx, `SELECT fingerprint, source_id, repo, test_name, issue_number, source, verdict, actor, created_at
+		FROM feedback `+where+` ORDER BY created_at`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Feedback
+	for rows.Next() {
+		var f Feedback
+		if err := rows.Scan(&f.Fingerprint, &f.SourceID, &f.Repo, &f.TestName, &f.IssueNumber, &f.Source, &f.Verdict, &f.Actor, &f.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, f)
+	}
+	return out, rows.Err()
+}
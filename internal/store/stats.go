@@ -0,0 +1,249 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+)
+
+// OccurrenceStats aggregates every occurrence of a fingerprint. Weekly covers
+// the weeks from since to now, oldest first, including weeks without
+// failures.
+type OccurrenceStats struct {
+	Total        int
+	DistinctSHAs int
+	Branches     []Count
+	Platforms    []Count
+	Weekly       []WeeklyCount
+}
+
+// Count is how many occurrences share a value, e.g. a branch.
+type Count struct {
+	Name  string
+	Count int
+}
+
+type WeeklyCount struct {
+	// Week is the Monday 00:00 UTC the week starts at.
+	Week  time.Time
+	Count int
+}
+
+func weekStart(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
+
+// weeklyCounts buckets times into the weeks from since to now.
+func weeklyCounts(times []time.Time, since, now time.Time) []WeeklyCount {
+	byWeek := map[time.Time]int{}
+	for _, t := range times {
+		if !t.Before(since) {
+			byWeek[weekStart(t)]++
+		}
+	}
+	var out []WeeklyCount
+	for w := weekStart(since); !w.After(now); w = w.AddDate(0, 0, 7) {
+		out = append(out, WeeklyCount{Week: w, Count: byWeek[w]})
+	}
+	return out
+}
+
+// sortedCounts orders counts by count, most frequent first, then by name.
+func sortedCounts(m map[string]int) []Count {
+	out := make([]Count, 0, len(m))
+	for name, n := range m {
+		out = append(out, Count{Name: name, Count: n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+func (m *Memory) GetOccurrenceStats(ctx context.Context, fingerprint string, since time.Time) (OccurrenceStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var stats OccurrenceStats
+	shas := map[string]bool{}
+	branches := map[string]int{}
+	platforms := map[string]int{}
+	var times []time.Time
+	for _, occ := range m.occurrences[fingerprint] {
+		stats.Total++
+		shas[occ.HeadSHA] = true
+		branches[occ.Branch]++
+		platforms[occ.RunnerOS]++
+		times = append(times, occ.OccurredAt)
+	}
+	stats.DistinctSHAs = len(shas)
+	stats.Branches = sortedCounts(branches)
+	stats.Platforms = sortedCounts(platforms)
+	stats.Weekly = weeklyCounts(times, since, time.Now())
+	return stats, nil
+}
+
+func (t *TiDBStore) GetOccurrenceStats(ctx context.Context, fingerprint string, since time.Time) (OccurrenceStats, error) {
+	var stats OccurrenceStats
+	row := t.db.QueryRowContext(ctx, `SELECT COUNT(*), COUNT(DISTINCT head_sha) FROM occurrences WHERE fingerprint = ?`, fingerprint)
+	if err := row.Scan(&stats.Total, &stats.DistinctSHAs); err != nil {
+		return OccurrenceStats{}, err
+	}
+	var err error
+	if stats.Branches, err = t.countOccurrencesBy(ctx, "branch", fingerprint); err != nil {
+		return OccurrenceStats{}, err
+	}
+	if stats.Platforms, err = t.countOccurrencesBy(ctx, "runner_os", fingerprint); err != nil {
+		return OccurrenceStats{}, err
+	}
+
+	rows, err := t.db.QueryContext(ctx, `SELECT occurred_at FROM occurrences WHERE fingerprint = ? AND occurred_at >= ?`, fingerprint, since)
+	if err != nil {
+		return OccurrenceStats{}, err
+	}
+	defer rows.Close()
+	var times []time.Time
+	for rows.Next() {
+		var at time.Time
+		if err := rows.Scan(&at); err != nil {
+			return OccurrenceStats{}, err
+		}
+		times = append(times, at)
+	}
+	if err := rows.Err(); err != nil {
+		return OccurrenceStats{}, err
+	}
+	stats.Weekly = weeklyCounts(times, since, time.Now())
+	return stats, nil
+}
+
+// countOccurrencesBy counts the occurrences of fingerprint per value of
+// column, which must be a trusted column name.
+func (t *TiDBStore) countOccurrencesBy(ctx context.Context, column, fingerprint string) ([]Count, error) {
+	rows, err := t.db.QueryContext(ctx, `SELECT `+column+`, COUNT(*) FROM occurrences WHERE fingerprint = ? GROUP BY `+column, fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := map[string]int{}
+	for rows.Next() {
+		var name string
+		var n int
+		if err := rows.Scan(&name, &n); err != nil {
+			return nil, err
+		}
+		counts[name] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return sortedCounts(counts), nil
+}
+
+// Evidence counts the occurrences of a fingerprint since a point in time,
+// for the gates an issue must pass before it is opened.
+type Evidence struct {
+	Occurrences  int
+	DistinctSHAs int
+	// BranchOccurrences counts the occurrences on the requested branch.
+	BranchOccurrences int
+}
+
+func (m *Memory) GetEvidence(ctx context.Context, fingerprint string, since time.Time, branch string) (Evidence, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var e Evidence
+	shas := map[string]bool{}
+	for _, occ := range m.occurrences[fingerprint] {
+		if occ.OccurredAt.Before(since) {
+			continue
+		}
+		e.Occurrences++
+		shas[occ.HeadSHA] = true
+		if occ.Branch == branch {
+			e.BranchOccurrences++
+		}
+	}
+	e.DistinctSHAs = len(shas)
+	return e, nil
+}
+
+func (t *TiDBStore) GetEvidence(ctx context.Context, fingerprint string, since time.Time, branch string) (Evidence, error) {
+	var e Evidence
+	row := t.db.QueryRowContext(ctx, `SELECT COUNT(*), COUNT(DISTINCT head_sha), COALESCE(SUM(branch = ?), 0)
+		FROM occurrences WHERE fingerprint = ? AND occurred_at >= ?`, branch, fingerprint, since)
+	if err := row.Scan(&e.Occurrences, &e.DistinctSHAs, &e.BranchOccurrences); err != nil {
+		return Evidence{}, err
+	}
+	return e, nil
+}
+
+// PeriodSummary sums up the occurrences of one fingerprint since a point in
+// time, for the digest.
+type PeriodSummary struct {
+	Fingerprint string
+	Count       int
+	// FirstAt is the first occurrence since the start of the period,
+	// PreviousAt the last one before it (zero if none).
+	FirstAt    time.Time
+	PreviousAt time.Time
+	// Package is a package the fingerprint's test failed in, if known.
+	Package string
+}
+
+func (m *Memory) SummarizeOccurrences(ctx context.Context, since time.Time) ([]PeriodSummary, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []PeriodSummary
+	for fp, list := range m.occurrences {
+		s := PeriodSummary{Fingerprint: fp}
+		for _, occ := range list {
+			if occ.OccurredAt.Before(since) {
+				if occ.OccurredAt.After(s.PreviousAt) {
+					s.PreviousAt = occ.OccurredAt
+				}
+				continue
+			}
+			s.Count++
+			if s.FirstAt.IsZero() || occ.OccurredAt.Before(s.FirstAt) {
+				s.FirstAt = occ.OccurredAt
+			}
+			if occ.Package > s.Package {
+				s.Package = occ.Package
+			}
+		}
+		if s.Count > 0 {
+			out = append(out, s)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Fingerprint < out[j].Fingerprint })
+	return out, nil
+}
+
+func (t *TiDBStore) SummarizeOccurrences(ctx context.Context, since time.Time) ([]PeriodSummary, error) {
+	rows, err := t.db.QueryContext(ctx, `SELECT o.fingerprint, COUNT(*), MIN(o.occurred_at), MAX(o.package),
+			(SELECT MAX(p.occurred_at) FROM occurrences p WHERE p.fingerprint = o.fingerprint AND p.occurred_at < ?)
+		FROM occurrences o WHERE o.occurred_at >= ? GROUP BY o.fingerprint ORDER BY o.fingerprint`, since, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []PeriodSummary
+	for rows.Next() {
+		var s PeriodSummary
+		var previous sql.NullTime
+		if err := rows.Scan(&s.Fingerprint, &s.Count, &s.FirstAt, &s.Package, &previous); err != nil {
+			return nil, err
+		}
+		if previous.Valid {
+			s.PreviousAt = previous.Time
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
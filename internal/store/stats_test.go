@@ -0,0 +1,139 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/okJiang/flaky-test-cleaner/internal/extract"
+)
+
+func TestWeeklyCounts(t *testing.T) {
+	// 2024-06-05 is a Wednesday.
+	since := time.Date(2024, 5, 22, 12, 0, 0, 0, time.UTC)
+	now := time.Date(2024, 6, 5, 9, 0, 0, 0, time.UTC)
+	times := []time.Time{
+		time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),  // before since
+		time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC), // same week as since, but earlier
+		time.Date(2024, 5, 23, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 6, 4, 23, 0, 0, 0, time.UTC),
+	}
+	got := weeklyCounts(times, since, now)
+	want := []WeeklyCount{
+		{Week: time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC), Count: 1},
+		{Week: time.Date(2024, 5, 27, 0, 0, 0, 0, time.UTC), Count: 0},
+		{Week: time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC), Count: 2},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d weeks, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if !got[i].Week.Equal(want[i].Week) || got[i].Count != want[i].Count {
+			t.Fatalf("week %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestMemoryOccurrenceStats(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	now := time.Now()
+	for i, occ := range []extract.Occurrence{
+		{HeadSHA: "a", Branch: "master", RunnerOS: "linux"},
+		{HeadSHA: "a", Branch: "master", RunnerOS: "macos"},
+		{HeadSHA: "b", Branch: "release-8.1", RunnerOS: "linux"},
+	} {
+		occ.Fingerprint = "fp"
+		occ.RunID = int64(i)
+		occ.OccurredAt = now.Add(-time.Duration(i) * time.Hour)
+		if err := m.UpsertOccurrence(ctx, occ); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stats, err := m.GetOccurrenceStats(ctx, "fp", now.AddDate(0, 0, -14))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Total != 3 || stats.DistinctSHAs != 2 {
+		t.Fatalf("unexpected totals %+v", stats)
+	}
+	if stats.Branches[0] != (Count{Name: "master", Count: 2}) || stats.Platforms[0] != (Count{Name: "linux", Count: 2}) {
+		t.Fatalf("unexpected counts %+v %+v", stats.Branches, stats.Platforms)
+	}
+	sum := 0
+	for _, w := range stats.Weekly {
+		sum += w.Count
+	}
+	if sum != 3 || len(stats.Weekly) < 3 {
+		t.Fatalf("unexpected weekly counts %+v", stats.Weekly)
+	}
+}
+
+func TestMemorySummarizeOccurrences(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	since := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)
+	for i, at := range []time.Time{since.AddDate(0, 0, -10), since.AddDate(0, 0, -3), since.Add(time.Hour), since.Add(2 * time.Hour)} {
+		occ := extract.Occurrence{Fingerprint: "fp", RunID: int64(i), OccurredAt: at}
+		if i == 3 {
+			occ.Package = "github.com/tikv/pd/pkg/foo"
+		}
+		if err := m.UpsertOccurrence(ctx, occ); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.UpsertOccurrence(ctx, extract.Occurrence{Fingerprint: "old", OccurredAt: since.AddDate(0, 0, -1)}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := m.SummarizeOccurrences(ctx, since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := PeriodSummary{Fingerprint: "fp", Count: 2, FirstAt: since.Add(time.Hour), PreviousAt: since.AddDate(0, 0, -3), Package: "github.com/tikv/pd/pkg/foo"}
+	if len(got) != 1 || got[0] != want {
+		t.Fatalf("SummarizeOccurrences = %+v, want %+v", got, want)
+	}
+}
+
+func TestMemoryEvidenceAndCandidate(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	now := time.Now()
+	for i, occ := range []extract.Occurrence{
+		{HeadSHA: "a", Branch: "master", OccurredAt: now.Add(-40 * 24 * time.Hour)},
+		{HeadSHA: "a", Branch: "pr-1", OccurredAt: now.Add(-2 * time.Hour)},
+		{HeadSHA: "b", Branch: "pr-2", OccurredAt: now.Add(-time.Hour)},
+	} {
+		occ.Fingerprint = "fp"
+		occ.RunID = int64(i)
+		if err := m.UpsertOccurrence(ctx, occ); err != nil {
+			t.Fatal(err)
+		}
+	}
+	e, err := m.GetEvidence(ctx, "fp", now.Add(-30*24*time.Hour), "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e != (Evidence{Occurrences: 2, DistinctSHAs: 2}) {
+		t.Fatalf("unexpected windowed evidence %+v", e)
+	}
+	e, err = m.GetEvidence(ctx, "fp", time.Time{}, "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e != (Evidence{Occurrences: 3, DistinctSHAs: 2, BranchOccurrences: 1}) {
+		t.Fatalf("unexpected evidence %+v", e)
+	}
+
+	if err := m.UpsertFingerprint(ctx, FingerprintRecord{Fingerprint: "fp"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetCandidate(ctx, "fp", true); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := m.GetFingerprint(ctx, "fp")
+	if err != nil || rec == nil || !rec.Candidate {
+		t.Fatalf("expected a candidate, got %+v err=%v", rec, err)
+	}
+}
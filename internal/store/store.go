@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"sync"
 	"time"
 
@@ -27,15 +28,53 @@ type FingerprintRecord struct {
 	PRNumber    int
 	FirstSeenAt time.Time
 	LastSeenAt  time.Time
+	// IssueContentHash and IssueUpdatedAt describe the last issue write, so
+	// unchanged evidence does not trigger another edit.
+	IssueContentHash string
+	IssueUpdatedAt   time.Time
+	// IssueState mirrors the linked issue as of the last sync; IssueClosedAt
+	// is set while it is closed.
+	IssueState    string
+	IssueClosedAt time.Time
+	// Candidate is set while the fingerprint would get an issue but lacks
+	// the evidence required to open one.
+	Candidate bool
 }
 
+// Linked issue states.
+const (
+	IssueStateOpen       = "open"
+	IssueStateClosed     = "closed"
+	IssueStateNotPlanned = "not_planned"
+)
+
 type Store interface {
 	Migrate(ctx context.Context) error
 	UpsertOccurrence(ctx context.Context, occ extract.Occurrence) error
 	UpsertFingerprint(ctx context.Context, rec FingerprintRecord) error
 	GetFingerprint(ctx context.Context, fingerprint string) (*FingerprintRecord, error)
 	ListRecentOccurrences(ctx context.Context, fingerprint string, limit int) ([]extract.Occurrence, error)
+	ListOccurrencesByTest(ctx context.Context, repo, testName string, since time.Time) ([]extract.Occurrence, error)
+	GetOccurrenceStats(ctx context.Context, fingerprint string, since time.Time) (OccurrenceStats, error)
+	GetEvidence(ctx context.Context, fingerprint string, since time.Time, branch string) (Evidence, error)
+	SummarizeOccurrences(ctx context.Context, since time.Time) ([]PeriodSummary, error)
 	LinkIssue(ctx context.Context, fingerprint string, issueNumber int) error
+	RecordIssueUpdate(ctx context.Context, fingerprint, contentHash string, at time.Time) error
+	SetIssueState(ctx context.Context, fingerprint, state string, closedAt time.Time) error
+	SetCandidate(ctx context.Context, fingerprint string, candidate bool) error
+	MoveOccurrences(ctx context.Context, from, to, signature string) (int, error)
+	UpsertFingerprintAlias(ctx context.Context, alias FingerprintAlias) error
+	ListFingerprintAliases(ctx context.Context, fingerprint string) ([]FingerprintAlias, error)
+	GetClassificationCache(ctx context.Context, fingerprint, evidenceHash string) (*ClassificationCacheEntry, error)
+	PutClassificationCache(ctx context.Context, entry ClassificationCacheEntry) error
+	UpsertJobAttempt(ctx context.Context, attempt JobAttempt) error
+	ListJobAttempts(ctx context.Context, runID int64) ([]JobAttempt, error)
+	RecordTestExecutions(ctx context.Context, execs []TestExecution) error
+	GetTestExecutionStats(ctx context.Context, repo, testName string, since time.Time) (TestExecutionStats, error)
+	ListFingerprints(ctx context.Context) ([]FingerprintRecord, error)
+	UpsertFeedback(ctx context.Context, f Feedback) error
+	ListFeedback(ctx context.Context, fingerprint string) ([]Feedback, error)
+	ListFeedbackByTest(ctx context.Context, repo, testName string) ([]Feedback, error)
 	Close() error
 }
 
@@ -43,10 +82,25 @@ type Memory struct {
 	mu          sync.Mutex
 	fps         map[string]FingerprintRecord
 	occurrences map[string][]extract.Occurrence
+	aliases     map[string][]FingerprintAlias
+
+	classifications map[string]ClassificationCacheEntry
+	attempts        map[int64][]JobAttempt
+	executions      map[string][]TestExecution
+	feedback        map[string][]Feedback
 }
 
 func NewMemory() *Memory {
-	return &Memory{fps: map[string]FingerprintRecord{}, occurrences: map[string][]extract.Occurrence{}}
+	return &Memory{
+		fps:         map[string]FingerprintRecord{},
+		occurrences: map[string][]extract.Occurrence{},
+		aliases:     map[string][]FingerprintAlias{},
+
+		classifications: map[string]ClassificationCacheEntry{},
+		attempts:        map[int64][]JobAttempt{},
+		executions:      map[string][]TestExecution{},
+		feedback:        map[string][]Feedback{},
+	}
 }
 
 func (m *Memory) Migrate(ctx context.Context) error { return nil }
@@ -98,6 +152,17 @@ func (m *Memory) GetFingerprint(ctx context.Context, fingerprint string) (*Finge
 	return &cpy, nil
 }
 
+func (m *Memory) ListFingerprints(ctx context.Context) ([]FingerprintRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]FingerprintRecord, 0, len(m.fps))
+	for _, rec := range m.fps {
+		out = append(out, rec)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Fingerprint < out[j].Fingerprint })
+	return out, nil
+}
+
 func (m *Memory) ListRecentOccurrences(ctx context.Context, fingerprint string, limit int) ([]extract.Occurrence, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -112,6 +177,21 @@ func (m *Memory) ListRecentOccurrences(ctx context.Context, fingerprint string,
 	return out, nil
 }
 
+func (m *Memory) ListOccurrencesByTest(ctx context.Context, repo, testName string, since time.Time) ([]extract.Occurrence, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []extract.Occurrence
+	for _, list := range m.occurrences {
+		for _, occ := range list {
+			if occ.Repo == repo && occ.TestName == testName && !occ.OccurredAt.Before(since) {
+				out = append(out, occ)
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].OccurredAt.After(out[j].OccurredAt) })
+	return out, nil
+}
+
 func (m *Memory) LinkIssue(ctx context.Context, fingerprint string, issueNumber int) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -124,6 +204,44 @@ func (m *Memory) LinkIssue(ctx context.Context, fingerprint string, issueNumber
 	return nil
 }
 
+func (m *Memory) RecordIssueUpdate(ctx context.Context, fingerprint, contentHash string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rec, ok := m.fps[fingerprint]
+	if !ok {
+		return errors.New("fingerprint not found")
+	}
+	rec.IssueContentHash = contentHash
+	rec.IssueUpdatedAt = at
+	m.fps[fingerprint] = rec
+	return nil
+}
+
+func (m *Memory) SetIssueState(ctx context.Context, fingerprint, state string, closedAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rec, ok := m.fps[fingerprint]
+	if !ok {
+		return errors.New("fingerprint not found")
+	}
+	rec.IssueState = state
+	rec.IssueClosedAt = closedAt
+	m.fps[fingerprint] = rec
+	return nil
+}
+
+func (m *Memory) SetCandidate(ctx context.Context, fingerprint string, candidate bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rec, ok := m.fps[fingerprint]
+	if !ok {
+		return errors.New("fingerprint not found")
+	}
+	rec.Candidate = candidate
+	m.fps[fingerprint] = rec
+	return nil
+}
+
 func (m *Memory) Close() error { return nil }
 
 type TiDBStore struct {
@@ -158,6 +276,9 @@ func (t *TiDBStore) Migrate(ctx context.Context) error {
 			run_id BIGINT NOT NULL,
 			run_url TEXT NOT NULL,
 			head_sha VARCHAR(64) NOT NULL,
+			run_attempt INT NOT NULL DEFAULT 1,
+			branch VARCHAR(255) NOT NULL DEFAULT '',
+			event VARCHAR(50) NOT NULL DEFAULT '',
 			job_id BIGINT NOT NULL,
 			job_name VARCHAR(200) NOT NULL,
 			runner_os VARCHAR(100) NOT NULL,
@@ -168,6 +289,40 @@ func (t *TiDBStore) Migrate(ctx context.Context) error {
 			excerpt MEDIUMTEXT NOT NULL,
 			PRIMARY KEY (fingerprint, run_id, job_id, test_name(128))
 		)`,
+		`ALTER TABLE occurrences ADD COLUMN IF NOT EXISTS branch VARCHAR(255) NOT NULL DEFAULT ''`,
+		`ALTER TABLE occurrences ADD COLUMN IF NOT EXISTS event VARCHAR(50) NOT NULL DEFAULT ''`,
+		`ALTER TABLE occurrences ADD COLUMN IF NOT EXISTS run_attempt INT NOT NULL DEFAULT 1`,
+		`ALTER TABLE occurrences ADD COLUMN IF NOT EXISTS package VARCHAR(512) NOT NULL DEFAULT ''`,
+		`ALTER TABLE occurrences ADD COLUMN IF NOT EXISTS go_version VARCHAR(50) NOT NULL DEFAULT ''`,
+		`ALTER TABLE occurrences ADD COLUMN IF NOT EXISTS race BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE occurrences ADD COLUMN IF NOT EXISTS failpoint BOOLEAN NOT NULL DEFAULT FALSE`,
+		`CREATE TABLE IF NOT EXISTS job_attempts (
+			run_id BIGINT NOT NULL,
+			attempt INT NOT NULL,
+			job_id BIGINT NOT NULL,
+			job_name VARCHAR(200) NOT NULL,
+			head_sha VARCHAR(64) NOT NULL,
+			conclusion VARCHAR(50) NOT NULL,
+			completed_at TIMESTAMP NULL,
+			PRIMARY KEY (run_id, attempt, job_id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS fingerprint_aliases (
+			alias VARCHAR(64) NOT NULL,
+			signature VARCHAR(255) NOT NULL DEFAULT '',
+			canonical VARCHAR(64) NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (alias, signature)
+		)`,
+		`CREATE TABLE IF NOT EXISTS test_executions (
+			repo VARCHAR(200) NOT NULL,
+			test_name VARCHAR(300) NOT NULL,
+			run_id BIGINT NOT NULL,
+			job_id BIGINT NOT NULL,
+			passed INT NOT NULL,
+			failed INT NOT NULL,
+			occurred_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (repo, test_name(128), run_id, job_id)
+		)`,
 		`CREATE TABLE IF NOT EXISTS fingerprints (
 			fingerprint VARCHAR(64) NOT NULL PRIMARY KEY,
 			repo VARCHAR(200) NOT NULL,
@@ -178,7 +333,38 @@ func (t *TiDBStore) Migrate(ctx context.Context) error {
 			issue_number INT NOT NULL DEFAULT 0,
 			pr_number INT NOT NULL DEFAULT 0,
 			first_seen_at TIMESTAMP NOT NULL,
-			last_seen_at TIMESTAMP NOT NULL
+			last_seen_at TIMESTAMP NOT NULL,
+			issue_content_hash VARCHAR(64) NOT NULL DEFAULT '',
+			issue_updated_at TIMESTAMP NULL,
+			issue_state VARCHAR(20) NOT NULL DEFAULT '',
+			issue_closed_at TIMESTAMP NULL,
+			candidate BOOLEAN NOT NULL DEFAULT FALSE
+		)`,
+		`ALTER TABLE fingerprints ADD COLUMN IF NOT EXISTS issue_content_hash VARCHAR(64) NOT NULL DEFAULT ''`,
+		`ALTER TABLE fingerprints ADD COLUMN IF NOT EXISTS issue_updated_at TIMESTAMP NULL`,
+		`ALTER TABLE fingerprints ADD COLUMN IF NOT EXISTS issue_state VARCHAR(20) NOT NULL DEFAULT ''`,
+		`ALTER TABLE fingerprints ADD COLUMN IF NOT EXISTS issue_closed_at TIMESTAMP NULL`,
+		`ALTER TABLE fingerprints ADD COLUMN IF NOT EXISTS candidate BOOLEAN NOT NULL DEFAULT FALSE`,
+		`CREATE TABLE IF NOT EXISTS classification_cache (
+			fingerprint VARCHAR(64) NOT NULL,
+			evidence_hash VARCHAR(64) NOT NULL,
+			model VARCHAR(100) NOT NULL,
+			payload TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (fingerprint, evidence_hash)
+		)`,
+		`CREATE TABLE IF NOT EXISTS feedback (
+			fingerprint VARCHAR(64) NOT NULL,
+			source_id VARCHAR(100) NOT NULL,
+			repo VARCHAR(200) NOT NULL,
+			test_name VARCHAR(300) NOT NULL,
+			issue_number INT NOT NULL,
+			source VARCHAR(50) NOT NULL,
+			verdict VARCHAR(50) NOT NULL,
+			actor VARCHAR(100) NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (fingerprint, source_id),
+			KEY idx_feedback_test (repo, test_name(128))
 		)`,
 		`CREATE TABLE IF NOT EXISTS audit_log (
 			id BIGINT AUTO_INCREMENT PRIMARY KEY,
@@ -207,15 +393,19 @@ func (t *TiDBStore) Migrate(ctx context.Context) error {
 
 func (t *TiDBStore) UpsertOccurrence(ctx context.Context, occ extract.Occurrence) error {
 	query := `INSERT INTO occurrences (
-		fingerprint, repo, workflow, run_id, run_url, head_sha, job_id, job_name, runner_os,
-		occurred_at, framework, test_name, error_signature, excerpt
-	) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?)
+		fingerprint, repo, workflow, run_id, run_url, head_sha, run_attempt, branch, event, job_id, job_name, runner_os,
+		occurred_at, framework, test_name, error_signature, excerpt, package, go_version, race, failpoint
+	) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)
 	ON DUPLICATE KEY UPDATE
 		occurred_at = VALUES(occurred_at),
-		excerpt = VALUES(excerpt)`
+		excerpt = VALUES(excerpt),
+		package = VALUES(package),
+		go_version = VALUES(go_version),
+		race = VALUES(race),
+		failpoint = VALUES(failpoint)`
 	_, err := t.db.ExecContext(ctx, query,
-		occ.Fingerprint, occ.Repo, occ.Workflow, occ.RunID, occ.RunURL, occ.HeadSHA, occ.JobID, occ.JobName, occ.RunnerOS,
-		occ.OccurredAt, occ.Framework, occ.TestName, occ.ErrorSignature, occ.Excerpt,
+		occ.Fingerprint, occ.Repo, occ.Workflow, occ.RunID, occ.RunURL, occ.HeadSHA, occ.RunAttempt, occ.Branch, occ.Event, occ.JobID, occ.JobName, occ.RunnerOS,
+		occ.OccurredAt, occ.Framework, occ.TestName, occ.ErrorSignature, occ.Excerpt, occ.Package, occ.GoVersion, occ.Race, occ.Failpoint,
 	)
 	return err
 }
@@ -241,11 +431,9 @@ func (t *TiDBStore) UpsertFingerprint(ctx context.Context, rec FingerprintRecord
 }
 
 func (t *TiDBStore) GetFingerprint(ctx context.Context, fingerprint string) (*FingerprintRecord, error) {
-	query := `SELECT fingerprint, repo, test_name, framework, class, confidence, issue_number, pr_number, first_seen_at, last_seen_at
-		FROM fingerprints WHERE fingerprint = ?`
-	row := t.db.QueryRowContext(ctx, query, fingerprint)
-	var rec FingerprintRecord
-	if err := row.Scan(&rec.Fingerprint, &rec.Repo, &rec.TestName, &rec.Framework, &rec.Class, &rec.Confidence, &rec.IssueNumber, &rec.PRNumber, &rec.FirstSeenAt, &rec.LastSeenAt); err != nil {
+	query := `SELECT ` + fingerprintColumns + ` FROM fingerprints WHERE fingerprint = ?`
+	rec, err := scanFingerprint(t.db.QueryRowContext(ctx, query, fingerprint))
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
 		}
@@ -254,23 +442,76 @@ func (t *TiDBStore) GetFingerprint(ctx context.Context, fingerprint string) (*Fi
 	return &rec, nil
 }
 
+func (t *TiDBStore) ListFingerprints(ctx context.Context) ([]FingerprintRecord, error) {
+	rows, err := t.db.QueryContext(ctx, `SELECT `+fingerprintColumns+` FROM fingerprints ORDER BY fingerprint`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []FingerprintRecord
+	for rows.Next() {
+		rec, err := scanFingerprint(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rec)
+	}
+	return out, rows.Err()
+}
+
+const fingerprintColumns = `fingerprint, repo, test_name, framework, class, confidence, issue_number, pr_number, first_seen_at, last_seen_at,
+		issue_content_hash, issue_updated_at, issue_state, issue_closed_at, candidate`
+
+func scanFingerprint(row interface{ Scan(...any) error }) (FingerprintRecord, error) {
+	var rec FingerprintRecord
+	var updated, closed *time.Time
+	if err := row.Scan(&rec.Fingerprint, &rec.Repo, &rec.TestName, &rec.Framework, &rec.Class, &rec.Confidence, &rec.IssueNumber, &rec.PRNumber, &rec.FirstSeenAt, &rec.LastSeenAt,
+		&rec.IssueContentHash, &updated, &rec.IssueState, &closed, &rec.Candidate); err != nil {
+		return FingerprintRecord{}, err
+	}
+	if updated != nil {
+		rec.IssueUpdatedAt = *updated
+	}
+	if closed != nil {
+		rec.IssueClosedAt = *closed
+	}
+	return rec, nil
+}
+
 func (t *TiDBStore) ListRecentOccurrences(ctx context.Context, fingerprint string, limit int) ([]extract.Occurrence, error) {
 	if limit <= 0 {
 		limit = 5
 	}
-	query := `SELECT repo, workflow, run_id, run_url, head_sha, job_id, job_name, runner_os,
-		occurred_at, framework, test_name, error_signature, excerpt, fingerprint
+	query := `SELECT ` + occurrenceColumns + `
 		FROM occurrences WHERE fingerprint = ? ORDER BY occurred_at DESC LIMIT ?`
 	rows, err := t.db.QueryContext(ctx, query, fingerprint, limit)
 	if err != nil {
 		return nil, err
 	}
+	return scanOccurrences(rows)
+}
+
+func (t *TiDBStore) ListOccurrencesByTest(ctx context.Context, repo, testName string, since time.Time) ([]extract.Occurrence, error) {
+	query := `SELECT ` + occurrenceColumns + `
+		FROM occurrences WHERE repo = ? AND test_name = ? AND occurred_at >= ? ORDER BY occurred_at DESC LIMIT 1000`
+	rows, err := t.db.QueryContext(ctx, query, repo, testName, since)
+	if err != nil {
+		return nil, err
+	}
+	return scanOccurrences(rows)
+}
+
+const occurrenceColumns = `repo, workflow, run_id, run_url, head_sha, run_attempt, branch, event, job_id, job_name, runner_os,
+		occurred_at, framework, test_name, error_signature, excerpt, fingerprint, package, go_version, race, failpoint`
+
+func scanOccurrences(rows *sql.Rows) ([]extract.Occurrence, error) {
 	defer rows.Close()
 	var out []extract.Occurrence
 	for rows.Next() {
 		var occ extract.Occurrence
-		if err := rows.Scan(&occ.Repo, &occ.Workflow, &occ.RunID, &occ.RunURL, &occ.HeadSHA, &occ.JobID, &occ.JobName, &occ.RunnerOS,
-			&occ.OccurredAt, &occ.Framework, &occ.TestName, &occ.ErrorSignature, &occ.Excerpt, &occ.Fingerprint); err != nil {
+		if err := rows.Scan(&occ.Repo, &occ.Workflow, &occ.RunID, &occ.RunURL, &occ.HeadSHA, &occ.RunAttempt, &occ.Branch, &occ.Event, &occ.JobID, &occ.JobName, &occ.RunnerOS,
+			&occ.OccurredAt, &occ.Framework, &occ.TestName, &occ.ErrorSignature, &occ.Excerpt, &occ.Fingerprint,
+			&occ.Package, &occ.GoVersion, &occ.Race, &occ.Failpoint); err != nil {
 			return nil, err
 		}
 		out = append(out, occ)
@@ -283,6 +524,21 @@ func (t *TiDBStore) LinkIssue(ctx context.Context, fingerprint string, issueNumb
 	return err
 }
 
+func (t *TiDBStore) RecordIssueUpdate(ctx context.Context, fingerprint, contentHash string, at time.Time) error {
+	_, err := t.db.ExecContext(ctx, `UPDATE fingerprints SET issue_content_hash = ?, issue_updated_at = ? WHERE fingerprint = ?`, contentHash, at, fingerprint)
+	return err
+}
+
+func (t *TiDBStore) SetIssueState(ctx context.Context, fingerprint, state string, closedAt time.Time) error {
+	_, err := t.db.ExecContext(ctx, `UPDATE fingerprints SET issue_state = ?, issue_closed_at = ? WHERE fingerprint = ?`, state, nullTime(closedAt), fingerprint)
+	return err
+}
+
+func (t *TiDBStore) SetCandidate(ctx context.Context, fingerprint string, candidate bool) error {
+	_, err := t.db.ExecContext(ctx, `UPDATE fingerprints SET candidate = ? WHERE fingerprint = ?`, candidate, fingerprint)
+	return err
+}
+
 func (t *TiDBStore) Close() error { return t.db.Close() }
 
 func (t *TiDBStore) ensureDatabase(ctx context.Context) error {